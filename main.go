@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/ardanlabs/conf"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/qubic/go-qubic-nodes/node"
 	"github.com/qubic/go-qubic-nodes/web"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -19,14 +27,40 @@ type Configuration struct {
 		PeerList                 []string      `conf:"default:5.39.222.64;82.197.173.130;82.197.173.129"`
 		PeerPort                 string        `conf:"default:21841"`
 		ExchangeTimeout          time.Duration `conf:"default:2s"`
+		ConnectionMaxAge         time.Duration `conf:"default:5m"`
+		MaxConcurrentDials       int           `conf:"default:50"`
+		RefreshDeadline          time.Duration `conf:"default:10s"`
 		MaxTickErrorThreshold    uint32        `conf:"default:50"`
 		ReliableTickRange        uint32        `conf:"default:30"`
+		MaxTickQuorum            string        `conf:"default:1"`
+		StallTimeout             time.Duration `conf:"default:2m"`
+		NodeSelection            string        `conf:"default:score"`
+		NodeSelectionTopNodes    int           `conf:"default:3"`
+		TargetTickMargin         time.Duration `conf:"default:10s"`
+		TargetTickMinOffset      uint32        `conf:"default:5"`
 		UsePublicPeers           bool          `conf:"default:false"`
 		PublicPeersExclude       []string
 		PublicPeersCleanInterval time.Duration `conf:"default:24h"`
+		PeerStoreFile            string
+		PeerStoreInterval        time.Duration `conf:"default:1m"`
 	}
 	Service struct {
 		TickerUpdateInterval time.Duration `conf:"default:15s"`
+		BootstrapBackoff     time.Duration `conf:"default:1s"`
+		BootstrapMaxBackoff  time.Duration `conf:"default:30s"`
+		MaxMissedRefreshes   int           `conf:"default:3"`
+		MinReliableNodes     int           `conf:"default:1"`
+		ListenAddress        string        `conf:"default::8080"`
+		TLSCertFile          string
+		TLSKeyFile           string
+		TLSReloadInterval    time.Duration `conf:"default:1m"`
+		ReadTimeout          time.Duration `conf:"default:10s"`
+		WriteTimeout         time.Duration `conf:"default:30s"`
+		IdleTimeout          time.Duration `conf:"default:2m"`
+		MaxHeaderBytes       int           `conf:"default:1048576"`
+		ShutdownTimeout      time.Duration `conf:"default:15s"`
+		HistoryFile          string
+		HistoryRetention     time.Duration `conf:"default:24h"`
 	}
 }
 
@@ -65,31 +99,57 @@ func run() error {
 	}
 	log.Printf("main: Config :\n%v\n", out)
 
-	peerDiscovery := createPeerDiscoveryStrategy(config)
-	peerManager := node.NewPeerManager(config.Qubic.PeerList, peerDiscovery, config.Qubic.PeerPort, config.Qubic.ExchangeTimeout)
-	container, err := node.NewNodeContainer(peerManager, config.Qubic.MaxTickErrorThreshold, config.Qubic.ReliableTickRange)
+	maxTickQuorum, err := node.ParseQuorum(config.Qubic.MaxTickQuorum)
 	if err != nil {
-		log.Printf("Error: %v\n", err)
+		return errors.Wrap(err, "parsing max tick quorum")
 	}
 
-	go func() {
-		ticker := time.NewTicker(config.Service.TickerUpdateInterval)
+	selectionPolicy, err := node.NewSelectionPolicy(config.Qubic.NodeSelection, config.Qubic.NodeSelectionTopNodes)
+	if err != nil {
+		return errors.Wrap(err, "creating node selection policy")
+	}
 
-		for {
-			select {
-			case <-ticker.C:
-				updateErr := container.Update()
-				if updateErr != nil {
-					log.Printf("Error: %v\n", updateErr)
-				}
-			}
-		}
+	workerPool := node.NewWorkerPool(config.Qubic.MaxConcurrentDials)
+	peerDiscovery := createPeerDiscoveryStrategy(config, workerPool)
+	connectionPool := node.NewConnectionPool(config.Qubic.PeerPort, config.Qubic.ExchangeTimeout, config.Qubic.ConnectionMaxAge)
+	defer connectionPool.Close()
+	peerManager := node.NewPeerManager(config.Qubic.PeerList, peerDiscovery, connectionPool, workerPool, config.Qubic.RefreshDeadline)
+	peerStore := createPeerStore(config, peerManager)
+	container := node.NewNodeContainer(peerManager, config.Qubic.MaxTickErrorThreshold, config.Qubic.ReliableTickRange, maxTickQuorum, selectionPolicy, config.Qubic.StallTimeout)
+
+	history, err := createTickHistory(config)
+	if err != nil {
+		return errors.Wrap(err, "creating tick history")
+	}
+	defer history.Close()
+	container.History = history
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	refreshDone := make(chan struct{})
+	go func() {
+		defer close(refreshDone)
+		refresh(ctx, config, container, peerStore, peerManager)
 	}()
 
 	log.Printf("Staring WebServer...\n")
 
 	handler := web.PeersHandler{
-		Container: container,
+		Container:           container,
+		TargetTickMargin:    config.Qubic.TargetTickMargin,
+		TargetTickMinOffset: config.Qubic.TargetTickMinOffset,
+	}
+
+	historyHandler := web.HistoryHandler{
+		History: history,
+	}
+
+	probesHandler := web.ProbesHandler{
+		Container:          container,
+		RefreshInterval:    config.Service.TickerUpdateInterval,
+		MaxMissedRefreshes: config.Service.MaxMissedRefreshes,
+		MinReliableNodes:   config.Service.MinReliableNodes,
 	}
 
 	router := http.NewServeMux()
@@ -97,17 +157,172 @@ func run() error {
 	router.HandleFunc("GET /status", handler.HandleStatus)
 	router.HandleFunc("GET /max-tick", handler.HandleMaxTick)
 	router.HandleFunc("POST /reliable-nodes", handler.GetReliableNodesWithMinimumTick)
+	router.HandleFunc("GET /nodes", handler.HandleNodes)
+	router.HandleFunc("GET /nodes/{address}", handler.HandleNode)
+	router.HandleFunc("GET /epoch", handler.HandleEpoch)
+	router.HandleFunc("GET /eta", handler.HandleEta)
+	router.HandleFunc("GET /target-tick", handler.HandleTargetTick)
+	router.HandleFunc("GET /events", handler.HandleEvents)
+	router.HandleFunc("GET /ws", handler.HandleWebSocket)
+	router.HandleFunc("GET /history/max-tick", historyHandler.HandleMaxTickHistory)
+	router.HandleFunc("GET /history/nodes/{address}", historyHandler.HandleNodeHistory)
+	router.Handle("GET /metrics", promhttp.Handler())
+	router.HandleFunc("GET /healthz", probesHandler.HandleLiveness)
+	router.HandleFunc("GET /readyz", probesHandler.HandleReadiness)
+
+	server, err := createServer(config, router)
+	if err != nil {
+		return errors.Wrap(err, "creating web server")
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		log.Printf("main: Listening on %s\n", server.Addr)
+		if server.TLSConfig != nil {
+			serverErrors <- server.ListenAndServeTLS("", "")
+		} else {
+			serverErrors <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serverErrors:
+		return errors.Wrap(err, "running web server")
+	case <-ctx.Done():
+	}
+
+	log.Printf("main: Shutting down...\n")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.Service.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return errors.Wrap(err, "shutting down web server")
+	}
+	select {
+	case <-refreshDone:
+	case <-shutdownCtx.Done():
+		log.Printf("main: Refresh did not stop in time.\n")
+	}
+	savePeers(peerStore, peerManager)
+	return nil
+}
 
-	return http.ListenAndServe(":8080", router)
+// refresh runs the container refresh loop and saves the peers periodically until the context is done. Canceling the
+// context aborts in-flight node connections.
+func refresh(ctx context.Context, config Configuration, container *node.Container, peerStore *node.PeerStore, peerManager *node.PeerManager) {
+	ticker := time.NewTicker(config.Service.TickerUpdateInterval)
+	defer ticker.Stop()
+	storeTicker := time.NewTicker(config.Qubic.PeerStoreInterval)
+	defer storeTicker.Stop()
 
+	storeDone := make(chan struct{})
+	go func() {
+		defer close(storeDone)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-storeTicker.C:
+				savePeers(peerStore, peerManager)
+			}
+		}
+	}()
+	container.Run(ctx, ticker.C, config.Service.BootstrapBackoff, config.Service.BootstrapMaxBackoff)
+	<-storeDone
+	log.Printf("main: Stopped refresh.\n")
+}
+
+// createServer configures TLS, if a certificate and key are set. Request contexts are canceled on shutdown to end
+// long-lived event streams and websocket connections.
+func createServer(config Configuration, handler http.Handler) (*http.Server, error) {
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:              config.Service.ListenAddress,
+		Handler:           handler,
+		ReadTimeout:       config.Service.ReadTimeout,
+		ReadHeaderTimeout: config.Service.ReadTimeout,
+		WriteTimeout:      config.Service.WriteTimeout,
+		IdleTimeout:       config.Service.IdleTimeout,
+		MaxHeaderBytes:    config.Service.MaxHeaderBytes,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+	server.RegisterOnShutdown(cancelBase)
+
+	certFile, keyFile := config.Service.TLSCertFile, config.Service.TLSKeyFile
+	if certFile == "" && keyFile == "" {
+		return server, nil
+	}
+	if certFile == "" || keyFile == "" {
+		cancelBase()
+		return nil, errors.New("TLS needs both a certificate and a key file")
+	}
+	reloader, err := web.NewCertificateReloader(certFile, keyFile, config.Service.TLSReloadInterval)
+	if err != nil {
+		cancelBase()
+		return nil, err
+	}
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	return server, nil
 }
 
-func createPeerDiscoveryStrategy(config Configuration) node.PeerDiscovery {
+func createPeerDiscoveryStrategy(config Configuration, workerPool *node.WorkerPool) node.PeerDiscovery {
 	if config.Qubic.UsePublicPeers {
 		log.Println("main: Using public peers")
-		return node.NewPublicPeerDiscovery(config.Qubic.PeerPort, config.Qubic.ExchangeTimeout, config.Qubic.PublicPeersExclude, config.Qubic.PublicPeersCleanInterval)
+		return node.NewPublicPeerDiscovery(config.Qubic.PeerPort, config.Qubic.ExchangeTimeout, config.Qubic.PublicPeersExclude, config.Qubic.PublicPeersCleanInterval, workerPool, config.Qubic.RefreshDeadline)
 	} else {
 		log.Println("main: Using static peers")
 		return &node.NoPeerDiscovery{}
 	}
 }
+
+// createPeerStore returns nil, if no peer store file is configured. Stored peers are only used as additional
+// bootstrap peers together with public peer discovery.
+func createPeerStore(config Configuration, peerManager *node.PeerManager) *node.PeerStore {
+	if config.Qubic.PeerStoreFile == "" {
+		return nil
+	}
+	if !config.Qubic.UsePublicPeers {
+		log.Println("main: Peer store is only used with public peers")
+		return nil
+	}
+	peerStore := node.NewPeerStore(config.Qubic.PeerStoreFile)
+
+	stored, err := peerStore.Load()
+	if err != nil {
+		log.Printf("main: Failed to load stored peers: %v\n", err)
+		return peerStore
+	}
+	peers := slices.DeleteFunc(stored.Peers, func(peer node.StoredPeer) bool {
+		return slices.ContainsFunc(config.Qubic.PublicPeersExclude, func(excluded string) bool {
+			return strings.TrimSpace(excluded) == peer.Address
+		})
+	})
+	peerManager.RestorePeers(peers)
+	log.Printf("main: Restored %d stored peers\n", len(peers))
+	return peerStore
+}
+
+// createTickHistory keeps the history in memory only, if no history file is configured.
+func createTickHistory(config Configuration) (*node.TickHistory, error) {
+	if config.Service.HistoryFile == "" {
+		return node.NewTickHistory(config.Service.HistoryRetention), nil
+	}
+	return node.OpenTickHistory(config.Service.HistoryFile, config.Service.HistoryRetention)
+}
+
+func savePeers(peerStore *node.PeerStore, peerManager *node.PeerManager) {
+	if peerStore == nil {
+		return
+	}
+	err := peerStore.Save(node.StoredPeers{
+		SavedAt: time.Now().UTC(),
+		Peers:   peerManager.ExportPeers(),
+	})
+	if err != nil {
+		log.Printf("main: Failed to save peers: %v\n", err)
+	}
+}
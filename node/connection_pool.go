@@ -0,0 +1,136 @@
+package node
+
+import (
+	"context"
+	qubic "github.com/qubic/go-node-connector"
+	"log"
+	"sync"
+	"time"
+)
+
+type connection struct {
+	client      *qubic.Client
+	connectedAt time.Time
+	node        Node
+	lock        sync.Mutex
+}
+
+func (c *connection) close() {
+	if c.client != nil {
+		_ = c.client.Close()
+		c.client = nil
+	}
+}
+
+// ConnectionPool keeps one connection and node per address open between refreshes. Connections are dialed lazily and
+// redialed after an error or once they are older than the maximum connection age, which also refreshes the peers.
+type ConnectionPool struct {
+	port              string
+	connectionTimeout time.Duration
+	maxConnectionAge  time.Duration
+	connections       map[string]*connection
+	lock              sync.Mutex
+}
+
+func NewConnectionPool(port string, connectionTimeout, maxConnectionAge time.Duration) *ConnectionPool {
+	return &ConnectionPool{
+		port:              port,
+		connectionTimeout: connectionTimeout,
+		maxConnectionAge:  maxConnectionAge,
+		connections:       make(map[string]*connection),
+	}
+}
+
+func (cp *ConnectionPool) get(host string) *connection {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	conn, ok := cp.connections[host]
+	if !ok {
+		conn = &connection{
+			node: Node{
+				Address: host,
+				Port:    cp.port,
+			},
+		}
+		cp.connections[host] = conn
+	}
+	return conn
+}
+
+// Poll updates the pooled node of the given host and returns a copy of it. Canceling the context aborts the exchange.
+func (cp *ConnectionPool) Poll(ctx context.Context, host string) (*Node, error) {
+	conn := cp.get(host)
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, cp.connectionTimeout)
+	defer cancel()
+
+	if conn.client != nil && time.Since(conn.connectedAt) > cp.maxConnectionAge {
+		conn.close()
+	}
+
+	reused := conn.client != nil
+	err := cp.update(ctx, conn)
+	if err != nil && reused && ctx.Err() == nil {
+		// the node might have closed the idle connection, try once more with a new one
+		err = cp.update(ctx, conn)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	node := conn.node
+	return &node, nil
+}
+
+func (cp *ConnectionPool) update(ctx context.Context, conn *connection) error {
+	if conn.client == nil {
+		start := time.Now()
+		client, err := newClient(ctx, conn.node.Address, conn.node.Port)
+		if err != nil {
+			conn.node.LastUpdateSuccess = false
+			return newConnectError(err)
+		}
+		conn.client = client
+		conn.connectedAt = time.Now()
+		conn.node.ConnectLatency = conn.connectedAt.Sub(start)
+		conn.node.Peers = client.Peers
+	}
+
+	err := conn.node.Update(ctx, conn.client)
+	if err != nil {
+		conn.close()
+		return err
+	}
+	return nil
+}
+
+// Remove closes the connection to the given host and forgets the node.
+func (cp *ConnectionPool) Remove(host string) {
+	cp.lock.Lock()
+	conn, ok := cp.connections[host]
+	delete(cp.connections, host)
+	cp.lock.Unlock()
+
+	if ok {
+		conn.lock.Lock()
+		defer conn.lock.Unlock()
+		conn.close()
+	}
+}
+
+func (cp *ConnectionPool) Close() {
+	cp.lock.Lock()
+	hosts := make([]string, 0, len(cp.connections))
+	for host := range cp.connections {
+		hosts = append(hosts, host)
+	}
+	cp.lock.Unlock()
+
+	for _, host := range hosts {
+		cp.Remove(host)
+	}
+	log.Printf("Closed %d pooled connections.\n", len(hosts))
+}
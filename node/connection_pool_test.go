@@ -0,0 +1,244 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/qubic/go-node-connector/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeQubicNode answers tick info requests like a qubic node. The tick is increased with every answer. A hanging node
+// reads requests without answering them.
+type fakeQubicNode struct {
+	listener    net.Listener
+	tick        atomic.Uint32
+	hanging     atomic.Bool
+	connections atomic.Int32
+	open        []net.Conn
+	lock        sync.Mutex
+}
+
+func startFakeQubicNode(t *testing.T, tick uint32) *fakeQubicNode {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fake := &fakeQubicNode{listener: listener}
+	fake.tick.Store(tick)
+	go fake.serve()
+	t.Cleanup(fake.stop)
+	return fake
+}
+
+func (f *fakeQubicNode) host() string {
+	return f.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+func (f *fakeQubicNode) port() string {
+	_, port, _ := net.SplitHostPort(f.listener.Addr().String())
+	return port
+}
+
+func (f *fakeQubicNode) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.connections.Add(1)
+		f.lock.Lock()
+		f.open = append(f.open, conn)
+		f.lock.Unlock()
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeQubicNode) handle(conn net.Conn) {
+	defer conn.Close()
+	first := true
+	for {
+		var header types.RequestResponseHeader
+		if err := binary.Read(conn, binary.BigEndian, &header); err != nil {
+			return
+		}
+		_, _ = io.CopyN(io.Discard, conn, int64(header.GetSize())-int64(binary.Size(header)))
+		if f.hanging.Load() {
+			continue
+		}
+
+		var response bytes.Buffer
+		if first {
+			// a qubic node sends its public peers after connecting, the client reads them with the first request
+			writePacket(&response, types.ExchangePublicPeers, [4][4]byte{{1, 2, 3, 4}, {2, 3, 4, 5}})
+			writePacket(&response, types.CurrentTickInfoResponse, types.TickInfo{Tick: f.tick.Load(), Epoch: 100})
+			first = false
+		} else {
+			writePacket(&response, types.CurrentTickInfoResponse, types.TickInfo{Tick: f.tick.Add(1), Epoch: 100})
+		}
+		if _, err := conn.Write(response.Bytes()); err != nil {
+			return
+		}
+	}
+}
+
+// dropConnections closes all open connections, like a node closing idle connections.
+func (f *fakeQubicNode) dropConnections() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, conn := range f.open {
+		_ = conn.Close()
+	}
+	f.open = nil
+}
+
+func (f *fakeQubicNode) stop() {
+	_ = f.listener.Close()
+	f.dropConnections()
+}
+
+func writePacket(buffer *bytes.Buffer, packetType uint8, data any) {
+	var header types.RequestResponseHeader
+	header.SetSize(uint32(binary.Size(header) + binary.Size(data)))
+	header.Type = packetType
+	_ = binary.Write(buffer, binary.BigEndian, header)
+	_ = binary.Write(buffer, binary.LittleEndian, data)
+}
+
+func TestConnectionPool_Poll_reusesConnection(t *testing.T) {
+	fake := startFakeQubicNode(t, 1000)
+	pool := NewConnectionPool(fake.port(), time.Second, time.Hour)
+	defer pool.Close()
+
+	node, err := pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1001), node.LastTick)
+	assert.Equal(t, []string{"1.2.3.4", "2.3.4.5"}, []string(node.Peers))
+	assert.True(t, node.LastUpdateSuccess)
+	assert.True(t, node.ConnectLatency > 0)
+	assert.True(t, node.TickInfoLatency > 0)
+
+	node, err = pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1002), node.LastTick)
+	assert.Equal(t, []string{"1.2.3.4", "2.3.4.5"}, []string(node.Peers))
+	assert.Equal(t, int32(1), fake.connections.Load())
+}
+
+func TestConnectionPool_Poll_redialsClosedConnection(t *testing.T) {
+	fake := startFakeQubicNode(t, 1000)
+	pool := NewConnectionPool(fake.port(), time.Second, time.Hour)
+	defer pool.Close()
+
+	_, err := pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+
+	fake.dropConnections()
+
+	node, err := pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1002), node.LastTick)
+	assert.Equal(t, int32(2), fake.connections.Load())
+}
+
+func TestConnectionPool_Poll_redialsOldConnection(t *testing.T) {
+	fake := startFakeQubicNode(t, 1000)
+	pool := NewConnectionPool(fake.port(), time.Second, time.Nanosecond)
+	defer pool.Close()
+
+	_, err := pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+	_, err = pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), fake.connections.Load())
+}
+
+func TestConnectionPool_Poll_returnsCopy(t *testing.T) {
+	fake := startFakeQubicNode(t, 1000)
+	pool := NewConnectionPool(fake.port(), time.Second, time.Hour)
+	defer pool.Close()
+
+	first, err := pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+	_, err = pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(1001), first.LastTick)
+}
+
+func TestConnectionPool_Poll_offlineNode(t *testing.T) {
+	fake := startFakeQubicNode(t, 1000)
+	fake.stop()
+	pool := NewConnectionPool(fake.port(), 100*time.Millisecond, time.Hour)
+
+	_, err := pool.Poll(context.Background(), fake.host())
+	assert.Error(t, err)
+}
+
+func pollCanceled(t *testing.T, pool *ConnectionPool, host string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := pool.Poll(ctx, host)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, time.Since(start) < time.Second, "poll must return on cancel")
+}
+
+func TestConnectionPool_Poll_whenCanceledDuringConnect_thenReturnPromptly(t *testing.T) {
+	fake := startFakeQubicNode(t, 1000)
+	fake.hanging.Store(true)
+	pool := NewConnectionPool(fake.port(), 10*time.Second, time.Hour)
+	defer pool.Close()
+
+	pollCanceled(t, pool, fake.host())
+}
+
+func TestConnectionPool_Poll_whenCanceledDuringExchange_thenReturnPromptly(t *testing.T) {
+	fake := startFakeQubicNode(t, 1000)
+	pool := NewConnectionPool(fake.port(), 10*time.Second, time.Hour)
+	defer pool.Close()
+
+	_, err := pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+	fake.hanging.Store(true)
+
+	pollCanceled(t, pool, fake.host())
+}
+
+func TestConnectionPool_Remove(t *testing.T) {
+	fake := startFakeQubicNode(t, 1000)
+	pool := NewConnectionPool(fake.port(), time.Second, time.Hour)
+	defer pool.Close()
+
+	_, err := pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+
+	pool.Remove(fake.host())
+
+	_, err = pool.Poll(context.Background(), fake.host())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fake.connections.Load())
+}
+
+func TestConnectionPool_Poll_whenConnectionRefused_thenClassifyError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	pool := NewConnectionPool(port, time.Second, time.Hour)
+	defer pool.Close()
+
+	_, err = pool.Poll(context.Background(), "127.0.0.1")
+	require.Error(t, err)
+	assert.Equal(t, ErrorTypeConnectionRefused, classifyError(err))
+}
@@ -2,10 +2,12 @@ package node
 
 import (
 	"cmp"
+	"context"
 	"github.com/pkg/errors"
 	"log"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,133 +15,369 @@ type Container struct {
 	PeerManager        *PeerManager
 	TickErrorThreshold uint32
 	ReliableTickRange  uint32
-	OnlineNodes        []*Node
-	MaxTick            uint32
-	LastUpdate         int64
-	ReliableNodes      []*Node
-	MostReliableNode   *Node
+	MaxTickQuorum      Quorum
+	SelectionPolicy    SelectionPolicy
+	StallTimeout       time.Duration
+	History            *TickHistory
 	mutexLock          sync.RWMutex
+	snapshot           atomic.Pointer[Snapshot]
+	snapshots          []*Snapshot
+	events             eventBroker[ContainerEvent]
+	updates            eventBroker[ContainerUpdate]
+	updateHistory      []ContainerUpdate
+	initializing       bool
+	lastRefresh        time.Time
+	lastSuccess        time.Time
+	stallDetector      stallDetector
+	tickRateTracker    tickRateTracker
 }
 
 type ContainerResponse struct {
+	Sequence         uint64
 	MaxTick          uint32
 	LastUpdate       int64
 	ReliableNodes    []*Node
 	MostReliableNode *Node
+	OutlierNodes     []*TickOutlier
+	Degraded         bool
+	Initializing     bool
+	Stall            StallStatus
 }
 
-func NewNodeContainer(peerManager *PeerManager, tickErrorThreshold, reliableTickRange uint32) (*Container, error) {
-	container := Container{
+// NewNodeContainer returns a container in initializing state. Call Bootstrap to run the first refresh.
+func NewNodeContainer(peerManager *PeerManager, tickErrorThreshold, reliableTickRange uint32, maxTickQuorum Quorum, selectionPolicy SelectionPolicy, stallTimeout time.Duration) *Container {
+	return &Container{
 		PeerManager:        peerManager,
 		TickErrorThreshold: tickErrorThreshold,
 		ReliableTickRange:  reliableTickRange,
+		MaxTickQuorum:      maxTickQuorum,
+		SelectionPolicy:    selectionPolicy,
+		StallTimeout:       stallTimeout,
+		initializing:       true,
+		lastRefresh:        time.Now(),
 	}
-	err := container.Update()
-	if err != nil {
-		return nil, errors.Wrap(err, "updating container after initialization")
+}
+
+// Run bootstraps the container and then refreshes it on every tick until the context is done.
+func (c *Container) Run(ctx context.Context, ticks <-chan time.Time, initialBackoff, maxBackoff time.Duration) {
+	if err := c.Bootstrap(ctx, initialBackoff, maxBackoff); err != nil {
+		log.Printf("Bootstrap stopped: %v\n", err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Refresh stopped.\n")
+			return
+		case <-ticks:
+			err := c.Update(ctx)
+			if err != nil {
+				log.Printf("Error: %v\n", err)
+			}
+		}
 	}
+}
 
-	return &container, nil
+// Bootstrap refreshes until at least one node is online and a max tick is established. The delay between attempts starts with the initial backoff
+// and doubles up to the maximum backoff. The context error is returned, if the context is done before.
+func (c *Container) Bootstrap(ctx context.Context, initialBackoff, maxBackoff time.Duration) error {
+	backoff := initialBackoff
+	for {
+		err := c.Update(ctx)
+		if err == nil {
+			log.Printf("Bootstrap finished.\n")
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("Bootstrap failed: %v. Retrying in %s.\n", err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
 }
 
-func (c *Container) Update() error {
+// Update polls the nodes and recalculates the max tick and the reliable nodes. If the context is done during the
+// refresh the state is kept and the context error is returned.
+func (c *Container) Update(ctx context.Context) error {
 
 	log.Printf("<==========REFRESH==========>\n")
 	log.Printf("Refreshing nodes...\n")
+	start := time.Now()
 
-	onlineNodes := c.PeerManager.UpdateNodes()
-	maxTick := calculateMaxTick(onlineNodes, c.TickErrorThreshold)
+	onlineNodes := c.PeerManager.UpdateNodes(ctx)
+	if ctx.Err() != nil {
+		return errors.Wrap(ctx.Err(), "refreshing nodes")
+	}
+	onlineNodes = slices.Clone(onlineNodes) // the peer update reads the polled nodes in the background
+	slices.SortFunc(onlineNodes, func(a, b *Node) int {
+		return cmp.Compare(a.LastTick, b.LastTick)
+	})
+	maxTick, outlierNodes := calculateMaxTick(onlineNodes, c.TickErrorThreshold)
+
+	degraded := false
+	agreeingNodes := countNodesInRange(onlineNodes, maxTick, maxTick-c.ReliableTickRange)
+	if requiredNodes := c.MaxTickQuorum.RequiredNodes(len(onlineNodes)); agreeingNodes < requiredNodes {
+		previousMaxTick := c.GetSnapshot().MaxTick
+		log.Printf("No quorum for max tick %d: %d / %d required nodes agree. Keeping max tick %d.\n",
+			maxTick, agreeingNodes, requiredNodes, previousMaxTick)
+		maxTick = previousMaxTick
+		degraded = true
+	}
+
+	reliableMinimum := maxTick - c.ReliableTickRange
+	c.PeerManager.health.RecordSync(onlineNodes, maxTick, reliableMinimum)
 
-	reliableNodes, mostReliableNode := getReliableNodes(onlineNodes, maxTick, maxTick-c.ReliableTickRange)
+	reliableNodes := getReliableNodes(onlineNodes, maxTick, reliableMinimum)
 
-	c.Set(onlineNodes, maxTick, time.Now().UTC().Unix(), reliableNodes, mostReliableNode)
+	epoch, initialTick, tickDuration := calculateEpoch(onlineNodes, maxTick, reliableMinimum)
+	epochInfo := nextEpochInfo(c.GetEpoch(), epoch, initialTick, tickDuration, maxTick, time.Now())
+	reliableNodes, previousEpochNodes := excludePreviousEpochs(reliableNodes, epochInfo.Epoch)
+	outlierNodes = append(outlierNodes, previousEpochNodes...)
+
+	mostReliableNode := c.refreshSelectionPolicy().Select(reliableNodes, c.PeerManager.health.StatsOf(reliableNodes))
+
+	stall := c.stallDetector.observe(maxTick, len(onlineNodes), degraded, c.StallTimeout, time.Now())
+	tickRate := c.tickRateTracker.observe(maxTick, epochInfo.Epoch, time.Now())
+	c.Set(onlineNodes, maxTick, time.Now().UTC().Unix(), reliableNodes, mostReliableNode, outlierNodes, degraded, stall, epochInfo, tickRate)
+	if c.History != nil {
+		if err := c.History.Record(newTickRecord(c.GetSnapshot(), time.Now())); err != nil {
+			log.Printf("Failed to record tick history: %v\n", err)
+		}
+	}
+
+	refreshDurationHistogram.Observe(time.Since(start).Seconds())
+	recordRefreshMetrics(c, onlineNodes, reliableNodes, outlierNodes, maxTick, degraded)
+	recordStallMetrics(stall)
+	tickRateGauge.Set(tickRate.TicksPerSecond)
+	recordNodeErrorMetrics(c.GetNodes())
 
 	log.Printf("Node count: %d\n", c.GetNumberOfKnownNodes())
 	log.Printf("Max tick: %d\n", maxTick)
+	log.Printf("Epoch: %d\n", epochInfo.Epoch)
+	log.Printf("Tick rate: %.2f ticks/s\n", tickRate.TicksPerSecond)
 	log.Printf("Reliable nodes: %d / %d online\n", len(reliableNodes), len(onlineNodes))
 	if mostReliableNode != nil {
 		log.Printf("Most reliable node: %s\n", mostReliableNode.Address)
 	}
+	workerPoolStats := c.PeerManager.GetWorkerPoolStats()
+	log.Printf("Dial queue: %d peak, %d max in flight, %d timed out\n",
+		workerPoolStats.PeakQueued, workerPoolStats.MaxInFlight, workerPoolStats.TimedOut)
+	for _, outlier := range outlierNodes {
+		log.Printf("Rejected node %s: %s\n", outlier.Node.Address, outlier.Reason)
+	}
 
+	if len(onlineNodes) == 0 {
+		c.setRefreshed(false)
+		return errors.New("no online nodes found")
+	}
+	// without quorum on the first refresh there is no max tick yet
+	if maxTick == 0 {
+		c.setRefreshed(false)
+		return errors.New("no max tick established")
+	}
+	c.setRefreshed(true)
 	return nil
 }
 
-func (c *Container) Set(OnlineNodes []*Node, MaxTick uint32, LastUpdate int64, ReliableNodes []*Node, MostReliableNode *Node) {
+func (c *Container) setRefreshed(success bool) {
 	c.mutexLock.Lock()
 	defer c.mutexLock.Unlock()
+	c.lastRefresh = time.Now()
+	if success {
+		c.lastSuccess = c.lastRefresh
+		c.initializing = false
+	}
+}
 
-	c.OnlineNodes = OnlineNodes
-	c.MaxTick = MaxTick
-	c.LastUpdate = LastUpdate
-	c.ReliableNodes = ReliableNodes
-	c.MostReliableNode = MostReliableNode
+// GetEpoch returns the network epoch and the detected epoch transitions of the latest snapshot.
+func (c *Container) GetEpoch() EpochInfo {
+	return c.GetSnapshot().Epoch
 }
 
-func (c *Container) GetResponse() ContainerResponse {
+// GetTickRate returns the tick rate of the latest snapshot.
+func (c *Container) GetTickRate() TickRate {
+	return c.GetSnapshot().TickRate
+}
+
+// GetRefreshTimes returns when the last refresh and the last successful refresh finished. Before the first refresh
+// the last refresh is the creation time and the last successful refresh is zero.
+func (c *Container) GetRefreshTimes() (time.Time, time.Time) {
 	c.mutexLock.RLock()
 	defer c.mutexLock.RUnlock()
+	return c.lastRefresh, c.lastSuccess
+}
 
-	return ContainerResponse{
-		MaxTick:          c.MaxTick,
-		LastUpdate:       c.LastUpdate,
-		ReliableNodes:    c.ReliableNodes,
-		MostReliableNode: c.MostReliableNode,
+// IsInitializing returns true until the first refresh found an online node and established a max tick.
+func (c *Container) IsInitializing() bool {
+	c.mutexLock.RLock()
+	defer c.mutexLock.RUnlock()
+	return c.initializing
+}
+
+// refreshSelectionPolicy returns the policy that selects the most reliable node of a refresh. Policies that select
+// per request are applied by SelectMostReliableNode, the refresh keeps a stable pick by score for them.
+func (c *Container) refreshSelectionPolicy() SelectionPolicy {
+	if _, ok := c.SelectionPolicy.(perRequestSelection); ok || c.SelectionPolicy == nil {
+		return &ScoreSelection{}
 	}
+	return c.SelectionPolicy
 }
 
-func (c *Container) GetReliableNodesWithMinimumTick(tick uint32) []*Node {
+// SelectMostReliableNode returns the most reliable node for a request. Policies that spread the load across clients
+// select out of the reliable nodes of the response with every call, the others return the node of the refresh.
+func (c *Container) SelectMostReliableNode(response ContainerResponse) *Node {
+	policy, ok := c.SelectionPolicy.(perRequestSelection)
+	if !ok || len(response.ReliableNodes) == 0 {
+		return response.MostReliableNode
+	}
+	var stats map[string]HealthStats
+	if c.PeerManager != nil {
+		stats = c.PeerManager.health.StatsOf(response.ReliableNodes)
+	}
+	return policy.Select(response.ReliableNodes, stats)
+}
+
+// Set publishes a new snapshot with the next sequence number. The given nodes are copied.
+func (c *Container) Set(OnlineNodes []*Node, MaxTick uint32, LastUpdate int64, ReliableNodes []*Node, MostReliableNode *Node, OutlierNodes []*TickOutlier, Degraded bool, Stall StallStatus, Epoch EpochInfo, TickRate TickRate) {
+	c.mutexLock.Lock()
+
+	previous := c.GetSnapshot()
+	snapshot := newSnapshot(previous.Sequence+1, OnlineNodes, MaxTick, LastUpdate, ReliableNodes, MostReliableNode, OutlierNodes, Degraded, Stall, Epoch, TickRate)
+
+	event := newContainerEvent(previous.MaxTick, previous.ReliableNodes, previous.MostReliableNode, MaxTick, snapshot.ReliableNodes, snapshot.MostReliableNode, LastUpdate)
+	event.Epoch = snapshot.Epoch.Epoch
+	event.PreviousEpoch = previous.Epoch.Epoch
+	event.EpochChanged = event.Epoch != event.PreviousEpoch
+
+	c.snapshots = append(c.snapshots, snapshot)
+	if len(c.snapshots) > snapshotHistorySize {
+		c.snapshots = c.snapshots[1:]
+	}
+	c.snapshot.Store(snapshot)
+
+	update := newContainerUpdate(snapshot.Sequence, snapshot.OnlineNodes, MaxTick, LastUpdate, snapshot.ReliableNodes, snapshot.MostReliableNode, Degraded, snapshot.Epoch, event)
+	c.updateHistory = append(c.updateHistory, update)
+	if len(c.updateHistory) > updateHistorySize {
+		c.updateHistory = c.updateHistory[1:]
+	}
+	c.mutexLock.Unlock()
+
+	if event.HasChanges() {
+		c.events.publish(event)
+	}
+	c.updates.publish(update)
+}
+
+// SubscribeUpdates returns a channel that receives every container update. The returned function unsubscribes and
+// closes the channel.
+func (c *Container) SubscribeUpdates() (<-chan ContainerUpdate, func()) {
+	return c.updates.subscribe()
+}
+
+// GetUpdatesSince returns the kept updates with a sequence number greater than the given one. If updates after the
+// given sequence number are not kept anymore false is returned.
+func (c *Container) GetUpdatesSince(sequence uint64) ([]ContainerUpdate, bool) {
 	c.mutexLock.RLock()
 	defer c.mutexLock.RUnlock()
 
-	reliableNodesAtMinimumTick := make([]*Node, 0, len(c.ReliableNodes))
-	for _, node := range c.ReliableNodes {
-		if node.LastTick >= tick {
-			reliableNodesAtMinimumTick = append(reliableNodesAtMinimumTick, node)
-		}
+	current := c.GetSnapshot().Sequence
+	if sequence > current {
+		return nil, false
 	}
-
-	return reliableNodesAtMinimumTick
+	if sequence == current {
+		return nil, true
+	}
+	first := c.updateHistory[0].Sequence
+	if sequence+1 < first {
+		return nil, false
+	}
+	return slices.Clone(c.updateHistory[sequence+1-first:]), true
 }
 
-func (c *Container) GetNumberOfConfiguredNodes() int {
-	return c.PeerManager.GetNumberOfConfiguredNodes()
+// GetLatestUpdate returns the latest update. The sequence number is 0 before the first update.
+func (c *Container) GetLatestUpdate() ContainerUpdate {
+	c.mutexLock.RLock()
+	defer c.mutexLock.RUnlock()
+
+	if len(c.updateHistory) == 0 {
+		return ContainerUpdate{}
+	}
+	return c.updateHistory[len(c.updateHistory)-1]
 }
 
-func (c *Container) GetNumberOfKnownNodes() int {
-	return c.PeerManager.GetNumberOfKnownNodes()
+// Subscribe returns a channel that receives an event whenever the max tick, the reliable nodes or the most reliable
+// node change. The returned function unsubscribes and closes the channel.
+func (c *Container) Subscribe() (<-chan ContainerEvent, func()) {
+	return c.events.subscribe()
 }
 
-func calculateMaxTick(nodes []*Node, threshold uint32) uint32 {
-	slices.SortFunc(nodes, func(a, b *Node) int {
-		return cmp.Compare(a.LastTick, b.LastTick)
-	})
+// GetResponse returns the latest snapshot. The nodes are shared with the snapshot and must not be modified.
+func (c *Container) GetResponse() ContainerResponse {
+	return newContainerResponse(c.GetSnapshot(), c.IsInitializing())
+}
 
-	arrayLength := len(nodes)
+// GetResponseBySequence returns the snapshot with the given sequence number. False is returned, if the snapshot is
+// not kept anymore or does not exist yet.
+func (c *Container) GetResponseBySequence(sequence uint64) (ContainerResponse, bool) {
+	snapshot, ok := c.GetSnapshotBySequence(sequence)
+	if !ok {
+		return ContainerResponse{}, false
+	}
+	return newContainerResponse(snapshot, c.IsInitializing()), true
+}
 
-	if arrayLength == 0 {
-		return 0
+func newContainerResponse(snapshot *Snapshot, initializing bool) ContainerResponse {
+	return ContainerResponse{
+		Sequence:         snapshot.Sequence,
+		MaxTick:          snapshot.MaxTick,
+		LastUpdate:       snapshot.LastUpdate,
+		ReliableNodes:    snapshot.ReliableNodes,
+		MostReliableNode: snapshot.MostReliableNode,
+		OutlierNodes:     snapshot.OutlierNodes,
+		Degraded:         snapshot.Degraded,
+		Initializing:     initializing,
+		Stall:            snapshot.Stall,
 	}
+}
 
-	return nodes[arrayLength-1].LastTick
+// GetReliableNodesWithMinimumTick returns the reliable nodes of the latest snapshot at or above the given tick.
+func (c *Container) GetReliableNodesWithMinimumTick(tick uint32) []*Node {
+	return c.GetSnapshot().ReliableNodesWithMinimumTick(tick)
 }
 
-func getReliableNodes(onlineNodes []*Node, maximum, minimum uint32) ([]*Node, *Node) {
+func (c *Container) GetNumberOfConfiguredNodes() int {
+	return c.PeerManager.GetNumberOfConfiguredNodes()
+}
 
-	reliableNodes := make([]*Node, 0, len(onlineNodes))
+func (c *Container) GetNumberOfKnownNodes() int {
+	return c.PeerManager.GetNumberOfKnownNodes()
+}
+
+func getReliableNodes(onlineNodes []*Node, maximum, minimum uint32) []*Node {
 
-	var mostReliableNode *Node
+	reliableNodes := make([]*Node, 0, len(onlineNodes))
 
 	for _, node := range onlineNodes {
 
 		if node.LastTick >= minimum && node.LastTick <= maximum {
-
-			if node.LastTick == maximum {
-				mostReliableNode = node
-			}
-
 			reliableNodes = append(reliableNodes, node)
 		}
 	}
 
-	return reliableNodes, mostReliableNode
+	return reliableNodes
+}
+
+func countNodesInRange(nodes []*Node, maximum, minimum uint32) int {
+	count := 0
+	for _, node := range nodes {
+		if node.LastTick >= minimum && node.LastTick <= maximum {
+			count++
+		}
+	}
+	return count
 }
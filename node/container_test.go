@@ -1,21 +1,28 @@
 package node
 
 import (
+	"context"
+	"fmt"
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestMaxTick(t *testing.T) {
 
 	testData := []struct {
-		name      string
-		nodes     []*Node
-		threshold uint32
-		want      uint32
+		name         string
+		nodes        []*Node
+		threshold    uint32
+		want         uint32
+		wantOutliers []uint32
 	}{
 		{
-			name: "TestMaxTick_last_element",
+			name: "TestMaxTick_all_nodes_agree",
 			nodes: []*Node{
 				{
 					LastTick: 1000,
@@ -29,82 +36,143 @@ func TestMaxTick(t *testing.T) {
 				{
 					LastTick: 1023,
 				},
+			},
+			want:      1023,
+			threshold: 50,
+		},
+		{
+			name: "TestMaxTick_single_outlier_ahead",
+			nodes: []*Node{
 				{
-					LastTick: 1753,
+					LastTick: 1021,
 				},
 				{
-					LastTick: 1800,
-				}, {
-					LastTick: 100,
+					LastTick: 1000,
 				},
 				{
-					LastTick: 1945,
+					LastTick: 5700,
 				},
 				{
-					LastTick: 2000,
-				}, {
-					LastTick: 3000,
+					LastTick: 1023,
 				},
 			},
-			want:      3000,
-			threshold: 50,
+			want:         1023,
+			wantOutliers: []uint32{5700},
+			threshold:    50,
 		},
 		{
-			name: "TestMaxTick_element_in_middle_of_slice",
+			name: "TestMaxTick_highest_supported_cluster_wins",
 			nodes: []*Node{
 				{
-					LastTick: 1021,
+					LastTick: 999000,
 				},
 				{
-					LastTick: 1000,
+					LastTick: 999001,
 				},
 				{
-					LastTick: 1023,
+					LastTick: 999002,
 				},
 				{
-					LastTick: 1023,
+					LastTick: 1000000,
 				},
 				{
-					LastTick: 2500,
+					LastTick: 1000000,
 				},
 				{
-					LastTick: 5700,
-				}, {
+					LastTick: 2000000,
+				},
+			},
+			want:         1000000,
+			wantOutliers: []uint32{2000000},
+			threshold:    50,
+		},
+		{
+			name: "TestMaxTick_without_support_lowest_cluster_wins",
+			nodes: []*Node{
+				{
+					LastTick: 1000,
+				},
+				{
+					LastTick: 1500,
+				},
+				{
+					LastTick: 2000,
+				},
+			},
+			want:         1000,
+			wantOutliers: []uint32{1500, 2000},
+			threshold:    50,
+		},
+		{
+			name: "TestMaxTick_lagging_nodes_are_no_outliers",
+			nodes: []*Node{
+				{
 					LastTick: 100,
 				},
 				{
-					LastTick: 1945,
+					LastTick: 1999,
 				},
 				{
 					LastTick: 2000,
-				}, {
-					LastTick: 3000,
 				},
 			},
-			want:      5700,
+			want:      2000,
 			threshold: 50,
 		},
 		{
-			name: "TestMaxTick_one_element",
+			name: "TestMaxTick_tie_prefers_lower_cluster",
 			nodes: []*Node{
+				{
+					LastTick: 1500,
+				},
 				{
 					LastTick: 1000,
 				},
 			},
-			want:      1000,
+			want:         1000,
+			wantOutliers: []uint32{1500},
+			threshold:    50,
+		},
+		{
+			name: "TestMaxTick_gap_equal_threshold",
+			nodes: []*Node{
+				{
+					LastTick: 1000,
+				},
+				{
+					LastTick: 1001,
+				},
+				{
+					LastTick: 1051,
+				},
+			},
+			want:      1051,
 			threshold: 50,
 		},
 		{
-			name: "TestMaxTick_first_element",
+			name: "TestMaxTick_threshold_disabled",
 			nodes: []*Node{
 				{
-					LastTick: 1500,
+					LastTick: 1000,
 				},
+				{
+					LastTick: 1001,
+				},
+				{
+					LastTick: 5000,
+				},
+			},
+			want:      5000,
+			threshold: 0,
+		},
+		{
+			name: "TestMaxTick_one_element",
+			nodes: []*Node{
 				{
 					LastTick: 1000,
 				},
 			},
-			want:      1500,
+			want:      1000,
 			threshold: 50,
 		},
 		{
@@ -117,10 +185,17 @@ func TestMaxTick(t *testing.T) {
 
 	for _, test := range testData {
 		t.Run(test.name, func(t *testing.T) {
-			got := calculateMaxTick(test.nodes, test.threshold)
+			got, outliers := calculateMaxTick(test.nodes, test.threshold)
 			if got != test.want {
 				t.Fatalf("Want: %d Got: %d\n", test.want, got)
 			}
+
+			var outlierTicks []uint32
+			for _, outlier := range outliers {
+				require.NotEmpty(t, outlier.Reason)
+				outlierTicks = append(outlierTicks, outlier.Node.LastTick)
+			}
+			require.Equal(t, test.wantOutliers, outlierTicks)
 		})
 	}
 
@@ -193,10 +268,10 @@ func TestReliableNodes(t *testing.T) {
 			},
 			expectedReliableNodes: []*Node{
 				{
-					LastTick: 2048,
+					LastTick: 2049,
 				},
 				{
-					LastTick: 2049,
+					LastTick: 2048,
 				},
 				{
 					LastTick: 2050,
@@ -217,10 +292,10 @@ func TestReliableNodes(t *testing.T) {
 
 		t.Run(test.name, func(t *testing.T) {
 
-			maxTick := calculateMaxTick(test.nodes, test.maxTickThreshold)
+			maxTick, _ := calculateMaxTick(test.nodes, test.maxTickThreshold)
 			minTick := maxTick - test.reliableRange
 
-			reliableNodes, _ := getReliableNodes(test.nodes, maxTick, minTick)
+			reliableNodes := getReliableNodes(test.nodes, maxTick, minTick)
 
 			diff := cmp.Diff(reliableNodes, test.expectedReliableNodes)
 			require.Empty(t, diff)
@@ -337,10 +412,315 @@ func TestContainer_GetReliableNodesWithMinimumTick(t *testing.T) {
 	for _, test := range testData {
 		t.Run(test.name, func(t *testing.T) {
 			container := &Container{}
-			container.ReliableNodes = test.nodes
+			container.Set(nil, 0, 0, test.nodes, nil, nil, false, StallStatus{}, EpochInfo{}, TickRate{})
 			reliableNodesAtMinimumTick := container.GetReliableNodesWithMinimumTick(test.minimumTick)
 			diff := cmp.Diff(reliableNodesAtMinimumTick, test.expectedReliableNodes)
 			require.Empty(t, diff)
 		})
 	}
 }
+
+func createTestNodesWithTicks(ticks map[string]uint32) CreateNode {
+	return func(_ context.Context, host string) (*Node, error) {
+		tick, ok := ticks[host]
+		if !ok {
+			return nil, errors.Errorf("unknown test node [%s]", host)
+		}
+		node := createTestNode(host)
+		node.LastTick = tick
+		return node, nil
+	}
+}
+
+func TestContainer_Update_withQuorum_thenAdvanceMaxTick(t *testing.T) {
+	ticks := map[string]uint32{"1.2.3.4": 1000, "2.3.4.5": 1010, "3.4.5.6": 1040}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}, &NoPeerDiscovery{}, createTestNodesWithTicks(ticks), NewWorkerPool(10), time.Second)
+	container := &Container{
+		PeerManager:        peerManager,
+		TickErrorThreshold: 50,
+		ReliableTickRange:  30,
+		MaxTickQuorum:      NewQuorumCount(2),
+	}
+
+	err := container.Update(context.Background())
+	require.NoError(t, err)
+
+	response := container.GetResponse()
+	assert.Equal(t, uint32(1040), response.MaxTick)
+	assert.False(t, response.Degraded)
+	assert.Len(t, response.ReliableNodes, 2)
+}
+
+func TestContainer_Update_withoutQuorum_thenKeepPreviousMaxTick(t *testing.T) {
+	ticks := map[string]uint32{"1.2.3.4": 1000, "2.3.4.5": 1001, "3.4.5.6": 1045}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}, &NoPeerDiscovery{}, createTestNodesWithTicks(ticks), NewWorkerPool(10), time.Second)
+	container := &Container{
+		PeerManager:        peerManager,
+		TickErrorThreshold: 50,
+		ReliableTickRange:  30,
+		MaxTickQuorum:      NewQuorumPercent(50),
+	}
+	container.Set(nil, 1001, 0, nil, nil, nil, false, StallStatus{}, EpochInfo{}, TickRate{})
+
+	err := container.Update(context.Background())
+	require.NoError(t, err)
+
+	response := container.GetResponse()
+	assert.Equal(t, uint32(1001), response.MaxTick)
+	assert.True(t, response.Degraded)
+	assert.Len(t, response.ReliableNodes, 2)
+}
+
+func TestContainer_Bootstrap(t *testing.T) {
+	attempts := 0
+	createNode := func(_ context.Context, host string) (*Node, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("offline")
+		}
+		return createTestNode(host), nil
+	}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4"}, &NoPeerDiscovery{}, createNode, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+	assert.True(t, container.IsInitializing())
+	assert.True(t, container.GetResponse().Initializing)
+
+	err := container.Bootstrap(context.Background(), time.Millisecond, 2*time.Millisecond)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.False(t, container.IsInitializing())
+	assert.Equal(t, uint32(42), container.GetResponse().MaxTick)
+}
+
+func TestContainer_Bootstrap_withoutQuorum_thenStayInitializing(t *testing.T) {
+	var attempts atomic.Int32
+	createNode := func(_ context.Context, host string) (*Node, error) {
+		ticks := map[string]uint32{"1.2.3.4": 1000000, "2.3.4.5": 1000100, "3.4.5.6": 1000200}
+		if attempts.Add(1) > 6 { // the nodes agree from the third refresh on
+			ticks = map[string]uint32{"1.2.3.4": 1000200, "2.3.4.5": 1000200, "3.4.5.6": 1000200}
+		}
+		return createTestNodesWithTicks(ticks)(context.Background(), host)
+	}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}, &NoPeerDiscovery{}, createNode, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumPercent(50), nil, 0)
+
+	err := container.Update(context.Background())
+	assert.Error(t, err)
+	assert.True(t, container.IsInitializing())
+	_, lastSuccess := container.GetRefreshTimes()
+	assert.True(t, lastSuccess.IsZero())
+
+	err = container.Bootstrap(context.Background(), time.Millisecond, 2*time.Millisecond)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(9), attempts.Load())
+	assert.False(t, container.IsInitializing())
+	assert.Equal(t, uint32(1000200), container.GetResponse().MaxTick)
+	assert.False(t, container.GetResponse().Degraded)
+}
+
+func TestContainer_Bootstrap_whenCanceled_thenStop(t *testing.T) {
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"6.6.6.6"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := container.Bootstrap(ctx, time.Hour, time.Hour)
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, container.IsInitializing())
+}
+
+func TestContainer_Update_whenCanceled_thenAbortDialsAndKeepState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	dialing := make(chan struct{})
+	createNode := func(ctx context.Context, host string) (*Node, error) {
+		close(dialing)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4"}, &NoPeerDiscovery{}, createNode, NewWorkerPool(10), time.Hour)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+	go func() {
+		<-dialing
+		cancel()
+	}()
+
+	err := container.Update(ctx)
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, container.IsInitializing())
+	lastRefresh, _ := container.GetRefreshTimes()
+	assert.False(t, lastRefresh.IsZero())
+}
+
+func TestContainer_Run(t *testing.T) {
+	var tick uint32 = 100
+	createNode := func(_ context.Context, host string) (*Node, error) {
+		node := createTestNode(host)
+		node.LastTick = tick
+		return node, nil
+	}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4"}, &NoPeerDiscovery{}, createNode, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+	updates, unsubscribe := container.SubscribeUpdates()
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time)
+	done := make(chan struct{})
+
+	go func() {
+		container.Run(ctx, ticks, time.Millisecond, time.Millisecond)
+		close(done)
+	}()
+	assert.Equal(t, uint32(100), (<-updates).MaxTick)
+
+	tick = 110
+	ticks <- time.Now()
+	assert.Equal(t, uint32(110), (<-updates).MaxTick)
+
+	cancel()
+	<-done
+	assert.Equal(t, uint32(110), container.GetResponse().MaxTick)
+}
+
+func TestContainer_Update_withoutOnlineNodes_thenStayInitializing(t *testing.T) {
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"6.6.6.6"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+
+	err := container.Update(context.Background())
+
+	assert.Error(t, err)
+	assert.True(t, container.IsInitializing())
+}
+
+func TestContainer_GetRefreshTimes(t *testing.T) {
+	online := true
+	createNode := func(_ context.Context, host string) (*Node, error) {
+		if !online {
+			return nil, errors.New("offline")
+		}
+		return createTestNode(host), nil
+	}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4"}, &NoPeerDiscovery{}, createNode, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+
+	lastRefresh, lastSuccess := container.GetRefreshTimes()
+	assert.False(t, lastRefresh.IsZero())
+	assert.True(t, lastSuccess.IsZero())
+
+	assert.NoError(t, container.Update(context.Background()))
+	lastRefresh, lastSuccess = container.GetRefreshTimes()
+	assert.Equal(t, lastRefresh, lastSuccess)
+
+	online = false
+	assert.Error(t, container.Update(context.Background()))
+	refreshed, stillLastSuccess := container.GetRefreshTimes()
+	assert.True(t, refreshed.After(lastRefresh) || refreshed.Equal(lastRefresh))
+	assert.Equal(t, lastSuccess, stillLastSuccess)
+}
+
+func TestContainer_GetNodes(t *testing.T) {
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"2.3.4.5", "6.6.6.6", "1.2.3.4"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+	assert.NoError(t, container.Update(context.Background()))
+
+	nodes := container.GetNodes()
+
+	assert.Len(t, nodes, 3)
+	assert.Equal(t, "1.2.3.4", nodes[0].Node.Address)
+	assert.Equal(t, NodeStateReliable, nodes[0].State)
+	assert.Equal(t, NodeSourceConfigured, nodes[0].Source)
+	assert.Equal(t, uint32(42), nodes[0].Node.LastTick)
+	assert.True(t, nodes[0].HasHealth)
+	assert.Equal(t, 1.0, nodes[0].Health.SuccessRatio)
+	assert.Equal(t, "6.6.6.6", nodes[2].Node.Address)
+	assert.Equal(t, NodeStateOffline, nodes[2].State)
+	assert.Equal(t, 0.0, nodes[2].Health.SuccessRatio)
+}
+
+func TestContainer_GetNodes_skippedPeers(t *testing.T) {
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "6.6.6.6"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+	assert.NoError(t, container.Update(context.Background()))
+	peerManager.skippedPeers.set([]string{"1.2.3.4", "6.6.6.6", "7.7.7.7"})
+
+	nodes := container.GetNodes()
+
+	assert.Len(t, nodes, 2, "unknown peers are not listed")
+	assert.Equal(t, NodeStateReliable, nodes[0].State, "the online state of the snapshot wins")
+	assert.Equal(t, NodeStateSkipped, nodes[1].State)
+}
+
+func TestContainer_GetNode(t *testing.T) {
+	online := true
+	createNode := func(_ context.Context, host string) (*Node, error) {
+		if !online {
+			return nil, errors.New("connection refused")
+		}
+		return createTestNodeWithPeers(host, []string{"2.3.4.5"}), nil
+	}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4"}, &NoPeerDiscovery{}, createNode, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+	assert.NoError(t, container.Update(context.Background()))
+	online = false
+	assert.Error(t, container.Update(context.Background()))
+
+	detail, ok := container.GetNode("1.2.3.4")
+
+	assert.True(t, ok)
+	assert.Equal(t, NodeStateOffline, detail.State)
+	assert.Len(t, detail.Polls, 2)
+	assert.True(t, detail.Polls[0].Success)
+	assert.False(t, detail.Polls[1].Success)
+	assert.Equal(t, "connection refused", detail.Health.LastError)
+	assert.Equal(t, ErrorTypeOther, detail.Health.LastErrorType)
+	assert.False(t, detail.Health.LastSeen.IsZero())
+	assert.False(t, detail.Health.LastFailure.IsZero())
+	assert.Empty(t, detail.ReportedBy)
+
+	_, ok = container.GetNode("2.3.4.5")
+	assert.False(t, ok)
+}
+
+// blockingPeerDiscovery records the order of the nodes it gets for the cleanup, before and after it is released.
+type blockingPeerDiscovery struct {
+	started chan []string
+	release chan struct{}
+	after   []string
+}
+
+func (d *blockingPeerDiscovery) FindNewPeers(_ context.Context, _ []*Node, _ []string) []*Node {
+	return nil
+}
+
+func (d *blockingPeerDiscovery) CleanupPeers(currentNodes []*Node, _ []string, _ []string) []string {
+	d.started <- getHosts(currentNodes)
+	<-d.release
+	d.after = getHosts(currentNodes)
+	return nil
+}
+
+func TestContainer_Update_keepsNodesOfPeerUpdate(t *testing.T) {
+	ticks := map[string]uint32{}
+	var addresses []string
+	for i := range 10 {
+		address := fmt.Sprintf("10.0.0.%d", i)
+		addresses = append(addresses, address)
+		ticks[address] = 1010 - uint32(i)
+	}
+	discovery := &blockingPeerDiscovery{started: make(chan []string, 1), release: make(chan struct{})}
+	peerManager := newPeerManagerWithCreateNodeFunction(addresses, discovery, createTestNodesWithTicks(ticks), NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+
+	err := container.Update(context.Background())
+	require.NoError(t, err)
+	before := <-discovery.started
+	close(discovery.release)
+	peerManager.peerUpdateLock.Lock() // wait for the peer update
+	defer peerManager.peerUpdateLock.Unlock()
+
+	assert.Equal(t, before, discovery.after)
+	assert.Equal(t, uint32(1010), container.GetResponse().MaxTick)
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// number of epoch transitions kept
+const epochTransitionHistorySize = 16
+
+// EpochInfo is the network epoch agreed by the nodes around the max tick.
+type EpochInfo struct {
+	Epoch        uint16
+	InitialTick  uint32
+	TickDuration uint16
+	Transitions  []EpochTransition
+}
+
+// EpochTransition records when a new epoch was detected.
+type EpochTransition struct {
+	From        uint16
+	To          uint16
+	InitialTick uint32
+	MaxTick     uint32
+	DetectedAt  time.Time
+}
+
+// calculateEpoch returns the epoch reported by most nodes within the given tick range. Ties are broken by the higher
+// epoch. The initial tick is the one reported by most nodes of that epoch and the tick duration is their median.
+// The returned epoch is 0, if no node is within the range.
+func calculateEpoch(nodes []*Node, maximum, minimum uint32) (uint16, uint32, uint16) {
+	epochCounts := make(map[uint16]int)
+	for _, node := range nodes {
+		if node.LastTick >= minimum && node.LastTick <= maximum {
+			epochCounts[node.Epoch]++
+		}
+	}
+	if len(epochCounts) == 0 {
+		return 0, 0, 0
+	}
+	epoch := mostFrequent(epochCounts)
+
+	initialTickCounts := make(map[uint32]int)
+	var tickDurations []uint16
+	for _, node := range nodes {
+		if node.Epoch == epoch && node.LastTick >= minimum && node.LastTick <= maximum {
+			initialTickCounts[node.InitialTick]++
+			tickDurations = append(tickDurations, node.TickDuration)
+		}
+	}
+	slices.Sort(tickDurations)
+	return epoch, mostFrequent(initialTickCounts), tickDurations[len(tickDurations)/2]
+}
+
+func mostFrequent[T cmp.Ordered](counts map[T]int) T {
+	var result T
+	best := 0
+	for value, count := range counts {
+		if count > best || (count == best && value > result) {
+			result = value
+			best = count
+		}
+	}
+	return result
+}
+
+// excludePreviousEpochs splits the nodes into nodes on the given epoch or later and nodes on a previous epoch.
+func excludePreviousEpochs(nodes []*Node, epoch uint16) ([]*Node, []*TickOutlier) {
+	current := make([]*Node, 0, len(nodes))
+	var previous []*TickOutlier
+	for _, node := range nodes {
+		if node.Epoch < epoch {
+			previous = append(previous, &TickOutlier{
+				Node:   node,
+				Reason: fmt.Sprintf("previous epoch %d, network is on epoch %d", node.Epoch, epoch),
+			})
+		} else {
+			current = append(current, node)
+		}
+	}
+	return current, previous
+}
+
+// nextEpochInfo returns the epoch info after a refresh. The epoch never goes back, so an unknown or older epoch keeps
+// the current info.
+func nextEpochInfo(current EpochInfo, epoch uint16, initialTick uint32, tickDuration uint16, maxTick uint32, now time.Time) EpochInfo {
+	if epoch == 0 || epoch < current.Epoch {
+		return current
+	}
+	next := EpochInfo{
+		Epoch:        epoch,
+		InitialTick:  initialTick,
+		TickDuration: tickDuration,
+		Transitions:  current.Transitions,
+	}
+	if current.Epoch != 0 && epoch != current.Epoch {
+		log.Printf("Epoch transition from %d to %d at initial tick %d.\n", current.Epoch, epoch, initialTick)
+		transitions := append(slices.Clone(current.Transitions), EpochTransition{
+			From:        current.Epoch,
+			To:          epoch,
+			InitialTick: initialTick,
+			MaxTick:     maxTick,
+			DetectedAt:  now,
+		})
+		if len(transitions) > epochTransitionHistorySize {
+			transitions = transitions[1:]
+		}
+		next.Transitions = transitions
+	}
+	return next
+}
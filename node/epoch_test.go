@@ -0,0 +1,102 @@
+package node
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func nodeOnEpoch(address string, tick uint32, epoch uint16, initialTick uint32) *Node {
+	return &Node{Address: address, LastTick: tick, Epoch: epoch, InitialTick: initialTick, TickDuration: 2000}
+}
+
+func TestCalculateEpoch(t *testing.T) {
+	nodes := []*Node{
+		nodeOnEpoch("1.2.3.4", 1000, 100, 900),
+		nodeOnEpoch("2.3.4.5", 1000, 101, 990),
+		nodeOnEpoch("3.4.5.6", 999, 101, 990),
+		nodeOnEpoch("4.5.6.7", 500, 99, 100),
+		nodeOnEpoch("5.6.7.8", 400, 99, 100),
+		nodeOnEpoch("6.7.8.9", 300, 99, 100),
+	}
+
+	epoch, initialTick, tickDuration := calculateEpoch(nodes, 1000, 970)
+
+	assert.Equal(t, uint16(101), epoch)
+	assert.Equal(t, uint32(990), initialTick)
+	assert.Equal(t, uint16(2000), tickDuration)
+}
+
+func TestCalculateEpoch_whenTie_thenHigherEpoch(t *testing.T) {
+	nodes := []*Node{
+		nodeOnEpoch("1.2.3.4", 1000, 100, 900),
+		nodeOnEpoch("2.3.4.5", 1000, 101, 990),
+	}
+
+	epoch, initialTick, _ := calculateEpoch(nodes, 1000, 970)
+
+	assert.Equal(t, uint16(101), epoch)
+	assert.Equal(t, uint32(990), initialTick)
+}
+
+func TestCalculateEpoch_whenNoNodeInRange_thenUnknown(t *testing.T) {
+	epoch, initialTick, tickDuration := calculateEpoch([]*Node{nodeOnEpoch("1.2.3.4", 10, 100, 1)}, 1000, 970)
+
+	assert.Zero(t, epoch)
+	assert.Zero(t, initialTick)
+	assert.Zero(t, tickDuration)
+}
+
+func TestExcludePreviousEpochs(t *testing.T) {
+	current := nodeOnEpoch("1.2.3.4", 1000, 101, 990)
+	previous := nodeOnEpoch("2.3.4.5", 995, 100, 900)
+
+	nodes, excluded := excludePreviousEpochs([]*Node{current, previous}, 101)
+
+	assert.Equal(t, []*Node{current}, nodes)
+	assert.Equal(t, []*TickOutlier{{Node: previous, Reason: "previous epoch 100, network is on epoch 101"}}, excluded)
+}
+
+func TestNextEpochInfo(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	info := nextEpochInfo(EpochInfo{}, 100, 900, 2000, 950, now)
+	assert.Equal(t, EpochInfo{Epoch: 100, InitialTick: 900, TickDuration: 2000}, info)
+
+	// unknown and older epochs are ignored
+	assert.Equal(t, info, nextEpochInfo(info, 0, 0, 0, 960, now))
+	assert.Equal(t, info, nextEpochInfo(info, 99, 100, 2000, 960, now))
+
+	info = nextEpochInfo(info, 101, 990, 1500, 995, now)
+	assert.Equal(t, uint16(101), info.Epoch)
+	assert.Equal(t, uint32(990), info.InitialTick)
+	assert.Equal(t, []EpochTransition{{From: 100, To: 101, InitialTick: 990, MaxTick: 995, DetectedAt: now}}, info.Transitions)
+}
+
+func TestContainer_Update_excludesNodesOnPreviousEpoch(t *testing.T) {
+	nodes := map[string]*Node{
+		"1.2.3.4": nodeOnEpoch("1.2.3.4", 1000, 101, 990),
+		"2.3.4.5": nodeOnEpoch("2.3.4.5", 1000, 101, 990),
+		"3.4.5.6": nodeOnEpoch("3.4.5.6", 995, 100, 900),
+	}
+	createNode := func(_ context.Context, host string) (*Node, error) {
+		node := *nodes[host]
+		return &node, nil
+	}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}, &NoPeerDiscovery{}, createNode, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+
+	assert.NoError(t, container.Update(context.Background()))
+
+	response := container.GetResponse()
+	assert.Len(t, response.ReliableNodes, 2)
+	assert.Len(t, response.OutlierNodes, 1)
+	assert.Equal(t, "3.4.5.6", response.OutlierNodes[0].Node.Address)
+	assert.Equal(t, uint16(101), container.GetEpoch().Epoch)
+	assert.Equal(t, uint32(990), container.GetEpoch().InitialTick)
+
+	update := container.GetLatestUpdate()
+	assert.True(t, update.Event.EpochChanged)
+	assert.Equal(t, uint16(101), update.Epoch.Epoch)
+}
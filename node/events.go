@@ -0,0 +1,152 @@
+package node
+
+import (
+	"log"
+	"slices"
+	"sync"
+)
+
+// number of events buffered per subscriber before events are dropped for slow subscribers
+const subscriberBufferSize = 16
+
+// number of updates kept to let subscribers resume after a reconnect
+const updateHistorySize = 64
+
+// ContainerEvent describes the changes of a container update.
+type ContainerEvent struct {
+	MaxTick                 uint32
+	PreviousMaxTick         uint32
+	LastUpdate              int64
+	MaxTickChanged          bool
+	ReliableNodesAdded      []string
+	ReliableNodesRemoved    []string
+	MostReliableNode        string
+	MostReliableNodeChanged bool
+	Epoch                   uint16
+	PreviousEpoch           uint16
+	EpochChanged            bool
+}
+
+func (e ContainerEvent) HasChanges() bool {
+	return e.MaxTickChanged || e.MostReliableNodeChanged || e.EpochChanged || len(e.ReliableNodesAdded) > 0 || len(e.ReliableNodesRemoved) > 0
+}
+
+// ContainerUpdate is a copy of the container state after an update. Every update has a new sequence number.
+type ContainerUpdate struct {
+	Sequence         uint64
+	MaxTick          uint32
+	LastUpdate       int64
+	OnlineNodes      []Node
+	ReliableNodes    []Node
+	MostReliableNode *Node
+	Degraded         bool
+	Epoch            EpochInfo
+	Event            ContainerEvent
+}
+
+func newContainerUpdate(sequence uint64, onlineNodes []*Node, maxTick uint32, lastUpdate int64, reliableNodes []*Node,
+	mostReliableNode *Node, degraded bool, epoch EpochInfo, event ContainerEvent) ContainerUpdate {
+	update := ContainerUpdate{
+		Sequence:      sequence,
+		MaxTick:       maxTick,
+		LastUpdate:    lastUpdate,
+		OnlineNodes:   copyNodes(onlineNodes),
+		ReliableNodes: copyNodes(reliableNodes),
+		Degraded:      degraded,
+		Epoch:         epoch,
+		Event:         event,
+	}
+	if mostReliableNode != nil {
+		mostReliable := *mostReliableNode
+		update.MostReliableNode = &mostReliable
+	}
+	return update
+}
+
+func copyNodes(nodes []*Node) []Node {
+	copies := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		copies = append(copies, *node)
+	}
+	return copies
+}
+
+func newContainerEvent(previousMaxTick uint32, previousReliable []*Node, previousMostReliable *Node,
+	maxTick uint32, reliable []*Node, mostReliable *Node, lastUpdate int64) ContainerEvent {
+	previousAddresses := addressesOf(previousReliable)
+	addresses := addressesOf(reliable)
+	event := ContainerEvent{
+		MaxTick:          maxTick,
+		PreviousMaxTick:  previousMaxTick,
+		LastUpdate:       lastUpdate,
+		MaxTickChanged:   maxTick != previousMaxTick,
+		MostReliableNode: addressOf(mostReliable),
+	}
+	event.MostReliableNodeChanged = event.MostReliableNode != addressOf(previousMostReliable)
+	for _, address := range addresses {
+		if !slices.Contains(previousAddresses, address) {
+			event.ReliableNodesAdded = append(event.ReliableNodesAdded, address)
+		}
+	}
+	for _, address := range previousAddresses {
+		if !slices.Contains(addresses, address) {
+			event.ReliableNodesRemoved = append(event.ReliableNodesRemoved, address)
+		}
+	}
+	return event
+}
+
+func addressesOf(nodes []*Node) []string {
+	addresses := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		addresses = append(addresses, node.Address)
+	}
+	slices.Sort(addresses)
+	return addresses
+}
+
+func addressOf(node *Node) string {
+	if node == nil {
+		return ""
+	}
+	return node.Address
+}
+
+type eventBroker[T any] struct {
+	subscribers map[chan T]struct{}
+	lock        sync.Mutex
+}
+
+func (eb *eventBroker[T]) subscribe() (<-chan T, func()) {
+	eb.lock.Lock()
+	defer eb.lock.Unlock()
+
+	if eb.subscribers == nil {
+		eb.subscribers = make(map[chan T]struct{})
+	}
+	channel := make(chan T, subscriberBufferSize)
+	eb.subscribers[channel] = struct{}{}
+
+	unsubscribe := func() {
+		eb.lock.Lock()
+		defer eb.lock.Unlock()
+		if _, ok := eb.subscribers[channel]; ok {
+			delete(eb.subscribers, channel)
+			close(channel)
+		}
+	}
+	return channel, unsubscribe
+}
+
+func (eb *eventBroker[T]) publish(event T) {
+	eb.lock.Lock()
+	defer eb.lock.Unlock()
+
+	for channel := range eb.subscribers {
+		select {
+		case channel <- event:
+		default:
+			log.Printf("Dropped event for slow subscriber.\n")
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package node
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewContainerEvent(t *testing.T) {
+	nodeA := &Node{Address: "1.2.3.4"}
+	nodeB := &Node{Address: "2.3.4.5"}
+	nodeC := &Node{Address: "3.4.5.6"}
+
+	event := newContainerEvent(100, []*Node{nodeA, nodeB}, nodeA, 101, []*Node{nodeC, nodeB}, nodeB, 1500000000)
+
+	assert.True(t, event.HasChanges())
+	assert.True(t, event.MaxTickChanged)
+	assert.Equal(t, uint32(100), event.PreviousMaxTick)
+	assert.Equal(t, uint32(101), event.MaxTick)
+	assert.Equal(t, []string{"3.4.5.6"}, event.ReliableNodesAdded)
+	assert.Equal(t, []string{"1.2.3.4"}, event.ReliableNodesRemoved)
+	assert.Equal(t, "2.3.4.5", event.MostReliableNode)
+	assert.True(t, event.MostReliableNodeChanged)
+}
+
+func TestNewContainerEvent_noChanges(t *testing.T) {
+	nodeA := &Node{Address: "1.2.3.4"}
+	nodeB := &Node{Address: "2.3.4.5"}
+
+	event := newContainerEvent(100, []*Node{nodeA, nodeB}, nodeA, 100, []*Node{nodeB, nodeA}, nodeA, 1500000000)
+
+	assert.False(t, event.HasChanges())
+}
+
+func TestContainer_Subscribe(t *testing.T) {
+	container := &Container{}
+	events, unsubscribe := container.Subscribe()
+
+	nodeA := &Node{Address: "1.2.3.4", LastTick: 100}
+	container.Set([]*Node{nodeA}, 100, 1500000000, []*Node{nodeA}, nodeA, nil, false, StallStatus{}, EpochInfo{}, TickRate{})
+	// no changes, no event
+	container.Set([]*Node{nodeA}, 100, 1500000001, []*Node{nodeA}, nodeA, nil, false, StallStatus{}, EpochInfo{}, TickRate{})
+	container.Set([]*Node{nodeA}, 101, 1500000002, []*Node{nodeA}, nodeA, nil, false, StallStatus{}, EpochInfo{}, TickRate{})
+
+	first := <-events
+	assert.Equal(t, uint32(100), first.MaxTick)
+	assert.Equal(t, []string{"1.2.3.4"}, first.ReliableNodesAdded)
+	second := <-events
+	assert.Equal(t, uint32(101), second.MaxTick)
+	assert.Equal(t, int64(1500000002), second.LastUpdate)
+
+	unsubscribe()
+	_, open := <-events
+	require.False(t, open)
+}
+
+func TestEventBroker_dropsEventsForSlowSubscribers(t *testing.T) {
+	var broker eventBroker[int]
+	events, unsubscribe := broker.subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBufferSize+5; i++ {
+		broker.publish(i)
+	}
+
+	assert.Len(t, events, subscriberBufferSize)
+	assert.Equal(t, 0, <-events)
+}
+
+func TestContainer_GetUpdatesSince(t *testing.T) {
+	container := &Container{}
+	updates, ok := container.GetUpdatesSince(0)
+	assert.True(t, ok)
+	assert.Empty(t, updates)
+
+	nodeA := &Node{Address: "1.2.3.4", LastTick: 100}
+	for i := 0; i < updateHistorySize+10; i++ {
+		container.Set([]*Node{nodeA}, uint32(100+i), 1500000000, []*Node{nodeA}, nodeA, nil, false, StallStatus{}, EpochInfo{}, TickRate{})
+	}
+	latest := container.GetLatestUpdate()
+	assert.Equal(t, uint64(updateHistorySize+10), latest.Sequence)
+
+	updates, ok = container.GetUpdatesSince(latest.Sequence - 2)
+	require.True(t, ok)
+	require.Len(t, updates, 2)
+	assert.Equal(t, latest.Sequence-1, updates[0].Sequence)
+	assert.Equal(t, latest.Sequence, updates[1].Sequence)
+
+	updates, ok = container.GetUpdatesSince(latest.Sequence)
+	assert.True(t, ok)
+	assert.Empty(t, updates)
+
+	// too old
+	_, ok = container.GetUpdatesSince(5)
+	assert.False(t, ok)
+
+	// unknown
+	_, ok = container.GetUpdatesSince(latest.Sequence + 1)
+	assert.False(t, ok)
+}
@@ -0,0 +1,294 @@
+package node
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// number of samples kept per node
+const healthHistorySize = 100
+
+// latency at which the latency part of the score drops to one half
+const referenceLatency = 100 * time.Millisecond
+
+type availabilitySample struct {
+	at      time.Time
+	success bool
+	latency time.Duration
+}
+
+// PollSample is the result of a single poll of a node.
+type PollSample struct {
+	At      time.Time
+	Success bool
+	Latency time.Duration
+}
+
+type syncSample struct {
+	reliable bool
+	lag      uint32
+}
+
+type ring[T any] struct {
+	values []T
+	next   int
+}
+
+func (r *ring[T]) add(value T) {
+	if len(r.values) < healthHistorySize {
+		r.values = append(r.values, value)
+	} else {
+		r.values[r.next] = value
+	}
+	r.next = (r.next + 1) % healthHistorySize
+}
+
+// ordered returns the values from oldest to newest.
+func (r *ring[T]) ordered() []T {
+	if len(r.values) < healthHistorySize {
+		return slices.Clone(r.values)
+	}
+	return append(slices.Clone(r.values[r.next:]), r.values[:r.next]...)
+}
+
+type nodeHistory struct {
+	availability  ring[availabilitySample]
+	sync          ring[syncSample]
+	lastSeen      time.Time
+	lastFailure   time.Time
+	lastError     string
+	lastErrorType string
+}
+
+type HealthStats struct {
+	Samples       int
+	SuccessRatio  float64
+	LatencyP50    time.Duration
+	LatencyP90    time.Duration
+	LatencyP99    time.Duration
+	ReliableRatio float64
+	AverageLag    float64
+	MaxLag        uint32
+	LastSeen      time.Time
+	LastFailure   time.Time
+	LastError     string
+	LastErrorType string
+	Score         float64
+}
+
+// HealthTracker keeps a rolling history of poll results and tick lag per node address.
+type HealthTracker struct {
+	histories map[string]*nodeHistory
+	lock      sync.RWMutex
+}
+
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{
+		histories: make(map[string]*nodeHistory),
+	}
+}
+
+func (ht *HealthTracker) history(address string) *nodeHistory {
+	history, ok := ht.histories[address]
+	if !ok {
+		history = &nodeHistory{}
+		ht.histories[address] = history
+	}
+	return history
+}
+
+func (ht *HealthTracker) RecordPoll(address string, success bool, latency time.Duration) {
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	now := time.Now()
+	history := ht.history(address)
+	history.availability.add(availabilitySample{at: now, success: success, latency: latency})
+	if success {
+		history.lastSeen = now
+	} else {
+		history.lastFailure = now
+	}
+}
+
+// RecordError keeps the error and its type of the last failed poll.
+func (ht *HealthTracker) RecordError(address string, err error) {
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	history := ht.history(address)
+	history.lastError = err.Error()
+	history.lastErrorType = classifyError(err)
+}
+
+// RecordSync records for every online node if it was within the reliable range and how far it lagged behind max tick.
+func (ht *HealthTracker) RecordSync(onlineNodes []*Node, maxTick, reliableMinimum uint32) {
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	for _, node := range onlineNodes {
+		reliable := node.LastTick >= reliableMinimum && node.LastTick <= maxTick
+		ht.history(node.Address).sync.add(syncSample{reliable: reliable, lag: node.TickLag(maxTick)})
+	}
+}
+
+func (ht *HealthTracker) Remove(address string) {
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	delete(ht.histories, address)
+}
+
+// Export returns the history of the given addresses for persisting it.
+func (ht *HealthTracker) Export(addresses []string) []StoredPeer {
+	ht.lock.RLock()
+	defer ht.lock.RUnlock()
+
+	peers := make([]StoredPeer, 0, len(addresses))
+	for _, address := range addresses {
+		peer := StoredPeer{Address: address}
+		if history, ok := ht.histories[address]; ok {
+			peer.LastSeen = history.lastSeen
+			peer.LastFailure = history.lastFailure
+			peer.LastError = history.lastError
+			peer.LastErrorType = history.lastErrorType
+			for _, sample := range history.availability.ordered() {
+				peer.Availability = append(peer.Availability, StoredAvailability{At: sample.at, Success: sample.success, Latency: sample.latency})
+			}
+			for _, sample := range history.sync.ordered() {
+				peer.Sync = append(peer.Sync, StoredSync{Reliable: sample.reliable, Lag: sample.lag})
+			}
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+// Restore replaces the history of the given peers with the persisted one.
+func (ht *HealthTracker) Restore(peers []StoredPeer) {
+	ht.lock.Lock()
+	defer ht.lock.Unlock()
+
+	for _, peer := range peers {
+		history := &nodeHistory{lastSeen: peer.LastSeen, lastFailure: peer.LastFailure, lastError: peer.LastError,
+			lastErrorType: peer.LastErrorType}
+		for _, sample := range peer.Availability {
+			history.availability.add(availabilitySample{at: sample.At, success: sample.Success, latency: sample.Latency})
+		}
+		for _, sample := range peer.Sync {
+			history.sync.add(syncSample{reliable: sample.Reliable, lag: sample.Lag})
+		}
+		ht.histories[peer.Address] = history
+	}
+}
+
+func (ht *HealthTracker) Stats(address string) (HealthStats, bool) {
+	ht.lock.RLock()
+	defer ht.lock.RUnlock()
+
+	history, ok := ht.histories[address]
+	if !ok {
+		return HealthStats{}, false
+	}
+	return history.stats(), true
+}
+
+// Polls returns the recent polls of the given address from oldest to newest.
+func (ht *HealthTracker) Polls(address string) []PollSample {
+	ht.lock.RLock()
+	defer ht.lock.RUnlock()
+
+	history, ok := ht.histories[address]
+	if !ok {
+		return nil
+	}
+	samples := history.availability.ordered()
+	polls := make([]PollSample, 0, len(samples))
+	for _, sample := range samples {
+		polls = append(polls, PollSample{At: sample.at, Success: sample.success, Latency: sample.latency})
+	}
+	return polls
+}
+
+// StatsOf returns the health statistics of the given nodes. Nodes without history are not contained.
+func (ht *HealthTracker) StatsOf(nodes []*Node) map[string]HealthStats {
+	ht.lock.RLock()
+	defer ht.lock.RUnlock()
+
+	stats := make(map[string]HealthStats, len(nodes))
+	for _, node := range nodes {
+		if history, ok := ht.histories[node.Address]; ok {
+			stats[node.Address] = history.stats()
+		}
+	}
+	return stats
+}
+
+func (nh *nodeHistory) stats() HealthStats {
+	stats := HealthStats{
+		Samples:       len(nh.availability.values),
+		LastSeen:      nh.lastSeen,
+		LastFailure:   nh.lastFailure,
+		LastError:     nh.lastError,
+		LastErrorType: nh.lastErrorType,
+	}
+
+	var latencies []time.Duration
+	for _, sample := range nh.availability.values {
+		if sample.success {
+			latencies = append(latencies, sample.latency)
+		}
+	}
+	if stats.Samples > 0 {
+		stats.SuccessRatio = float64(len(latencies)) / float64(stats.Samples)
+	}
+	slices.Sort(latencies)
+	stats.LatencyP50 = percentile(latencies, 50)
+	stats.LatencyP90 = percentile(latencies, 90)
+	stats.LatencyP99 = percentile(latencies, 99)
+
+	var reliableCount int
+	var lagSum uint64
+	for _, sample := range nh.sync.values {
+		if sample.reliable {
+			reliableCount++
+		}
+		lagSum += uint64(sample.lag)
+		stats.MaxLag = max(stats.MaxLag, sample.lag)
+	}
+	if syncSamples := len(nh.sync.values); syncSamples > 0 {
+		stats.ReliableRatio = float64(reliableCount) / float64(syncSamples)
+		stats.AverageLag = float64(lagSum) / float64(syncSamples)
+	}
+
+	stats.Score = score(stats, len(nh.sync.values))
+	return stats
+}
+
+// score weights availability and time spent in the reliable range highest, followed by latency and lag. The result
+// is between 0 and 1. Without sync samples the node was never online and the lag does not count.
+func score(stats HealthStats, syncSamples int) float64 {
+	if stats.Samples == 0 {
+		return 0
+	}
+	latencyScore := 0.0
+	if stats.SuccessRatio > 0 {
+		latencyScore = 1 / (1 + float64(stats.LatencyP50)/float64(referenceLatency))
+	}
+	lagScore := 0.0
+	if syncSamples > 0 {
+		lagScore = 1 / (1 + stats.AverageLag)
+	}
+	return 0.4*stats.SuccessRatio + 0.3*stats.ReliableRatio + 0.2*latencyScore + 0.1*lagScore
+}
+
+// percentile expects sorted values and uses the nearest rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,83 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestHealthTracker_Stats(t *testing.T) {
+	tracker := NewHealthTracker()
+	tracker.RecordPoll("1.2.3.4", true, 10*time.Millisecond)
+	tracker.RecordPoll("1.2.3.4", true, 30*time.Millisecond)
+	tracker.RecordPoll("1.2.3.4", true, 20*time.Millisecond)
+	tracker.RecordPoll("1.2.3.4", false, 2*time.Second)
+
+	tracker.RecordSync([]*Node{{Address: "1.2.3.4", LastTick: 1000}}, 1000, 970)
+	tracker.RecordSync([]*Node{{Address: "1.2.3.4", LastTick: 950}}, 1000, 970)
+
+	stats, ok := tracker.Stats("1.2.3.4")
+	require.True(t, ok)
+	assert.Equal(t, 4, stats.Samples)
+	assert.Equal(t, 0.75, stats.SuccessRatio)
+	assert.Equal(t, 20*time.Millisecond, stats.LatencyP50)
+	assert.Equal(t, 30*time.Millisecond, stats.LatencyP90)
+	assert.Equal(t, 30*time.Millisecond, stats.LatencyP99)
+	assert.Equal(t, 0.5, stats.ReliableRatio)
+	assert.Equal(t, 25.0, stats.AverageLag)
+	assert.Equal(t, uint32(50), stats.MaxLag)
+	assert.False(t, stats.LastSeen.IsZero())
+	assert.True(t, stats.Score > 0 && stats.Score <= 1, "score out of range: %f", stats.Score)
+
+	_, ok = tracker.Stats("2.3.4.5")
+	assert.False(t, ok)
+}
+
+func TestHealthTracker_Stats_neverOnline_thenScoreZero(t *testing.T) {
+	tracker := NewHealthTracker()
+	tracker.RecordPoll("1.2.3.4", false, time.Second)
+	tracker.RecordError("1.2.3.4", newConnectError(errors.New("connection refused")))
+
+	stats, ok := tracker.Stats("1.2.3.4")
+	require.True(t, ok)
+	assert.Equal(t, 0.0, stats.Score)
+}
+
+func TestHealthTracker_keepsRollingWindow(t *testing.T) {
+	tracker := NewHealthTracker()
+	for i := 0; i < healthHistorySize; i++ {
+		tracker.RecordPoll("1.2.3.4", false, 0)
+	}
+	for i := 0; i < healthHistorySize/2; i++ {
+		tracker.RecordPoll("1.2.3.4", true, time.Millisecond)
+	}
+
+	stats, _ := tracker.Stats("1.2.3.4")
+	assert.Equal(t, healthHistorySize, stats.Samples)
+	assert.Equal(t, 0.5, stats.SuccessRatio)
+}
+
+func TestHealthTracker_Remove(t *testing.T) {
+	tracker := NewHealthTracker()
+	tracker.RecordPoll("1.2.3.4", true, time.Millisecond)
+	tracker.Remove("1.2.3.4")
+
+	_, ok := tracker.Stats("1.2.3.4")
+	assert.False(t, ok)
+}
+
+func TestHealthTracker_StatsOf_preferHealthyNodes(t *testing.T) {
+	tracker := NewHealthTracker()
+	healthy := &Node{Address: "1.2.3.4", LastTick: 1000}
+	flaky := &Node{Address: "2.3.4.5", LastTick: 1000}
+	for i := 0; i < 10; i++ {
+		tracker.RecordPoll(healthy.Address, true, 10*time.Millisecond)
+		tracker.RecordPoll(flaky.Address, i%2 == 0, 10*time.Millisecond)
+	}
+
+	stats := tracker.StatsOf([]*Node{healthy, flaky, {Address: "3.4.5.6"}})
+	assert.True(t, stats[healthy.Address].Score > stats[flaky.Address].Score)
+	assert.NotContains(t, stats, "3.4.5.6")
+}
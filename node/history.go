@@ -0,0 +1,264 @@
+package node
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TickRecord is the result of one refresh.
+type TickRecord struct {
+	At            time.Time         `json:"at"`
+	MaxTick       uint32            `json:"max_tick"`
+	ReliableNodes int               `json:"reliable_nodes"`
+	NodeTicks     map[string]uint32 `json:"node_ticks,omitempty"`
+}
+
+// NodeTick is the tick of a node in one refresh.
+type NodeTick struct {
+	At      time.Time
+	Tick    uint32
+	MaxTick uint32
+}
+
+// TickHistory keeps the refresh results for the retention period. If a file is set, every record is appended to it as
+// a JSON line and the file is compacted once it holds more expired records than kept ones.
+type TickHistory struct {
+	path        string
+	retention   time.Duration
+	records     []TickRecord
+	fileRecords int
+	file        *os.File
+	closed      bool
+	lock        sync.RWMutex
+}
+
+func NewTickHistory(retention time.Duration) *TickHistory {
+	return &TickHistory{retention: retention}
+}
+
+// OpenTickHistory loads the records of the given file and appends new records to it. A missing file is created.
+func OpenTickHistory(path string, retention time.Duration) (*TickHistory, error) {
+	history := &TickHistory{path: path, retention: retention}
+	records, err := readTickRecords(path)
+	if err != nil {
+		return nil, err
+	}
+	history.records = records
+	history.prune(time.Now())
+	if err := history.compact(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func readTickRecords(path string) ([]TickRecord, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "opening tick history")
+	}
+	defer file.Close()
+
+	var records []TickRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record TickRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// a partially written last line is expected after a crash
+			log.Printf("Skipped invalid tick history record: %v\n", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading tick history")
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].At.Before(records[j].At)
+	})
+	return records, nil
+}
+
+// Record adds the record and drops the records older than the retention period. If the file could not be reopened
+// after a compaction, it is rewritten with all kept records.
+func (th *TickHistory) Record(record TickRecord) error {
+	th.lock.Lock()
+	defer th.lock.Unlock()
+
+	th.records = append(th.records, record)
+	th.prune(record.At)
+	if th.path == "" || th.closed {
+		return nil
+	}
+	if th.file == nil || th.fileRecords > 2*len(th.records) {
+		return th.compact()
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "encoding tick record")
+	}
+	_, err = th.file.Write(append(data, '\n'))
+	if err != nil {
+		return errors.Wrap(err, "writing tick record")
+	}
+	th.fileRecords++
+	return nil
+}
+
+func (th *TickHistory) prune(now time.Time) {
+	if th.retention <= 0 {
+		return
+	}
+	oldest := now.Add(-th.retention)
+	index, _ := slices.BinarySearchFunc(th.records, oldest, func(record TickRecord, at time.Time) int {
+		return record.At.Compare(at)
+	})
+	th.records = slices.Delete(th.records, 0, index)
+}
+
+// compact rewrites the file with the kept records and reopens it for appending. If the rewrite fails, the records are
+// still appended to the old file.
+func (th *TickHistory) compact() error {
+	temp, err := os.CreateTemp(filepath.Dir(th.path), filepath.Base(th.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating temporary tick history")
+	}
+	defer os.Remove(temp.Name())
+
+	writer := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(writer)
+	for _, record := range th.records {
+		if err = encoder.Encode(record); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "writing temporary tick history")
+	}
+	if err := os.Rename(temp.Name(), th.path); err != nil {
+		return errors.Wrap(err, "replacing tick history")
+	}
+
+	// the old file is replaced, appending to it would lose the records
+	if th.file != nil {
+		if err := th.file.Close(); err != nil {
+			log.Printf("Failed to close replaced tick history: %v\n", err)
+		}
+		th.file = nil
+	}
+	file, err := os.OpenFile(th.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "opening tick history")
+	}
+	th.file = file
+	th.fileRecords = len(th.records)
+	return nil
+}
+
+// Close closes the file. Records are only kept in memory afterward.
+func (th *TickHistory) Close() error {
+	th.lock.Lock()
+	defer th.lock.Unlock()
+	th.closed = true
+	if th.file == nil {
+		return nil
+	}
+	err := th.file.Close()
+	th.file = nil
+	return errors.Wrap(err, "closing tick history")
+}
+
+// newTickRecord returns the record of the refresh that created the snapshot.
+func newTickRecord(snapshot *Snapshot, at time.Time) TickRecord {
+	record := TickRecord{
+		At:            at,
+		MaxTick:       snapshot.MaxTick,
+		ReliableNodes: len(snapshot.ReliableNodes),
+		NodeTicks:     make(map[string]uint32, len(snapshot.OnlineNodes)),
+	}
+	for _, node := range snapshot.OnlineNodes {
+		record.NodeTicks[node.Address] = node.LastTick
+	}
+	return record
+}
+
+// Range returns the records from (inclusive) to (exclusive), downsampled to the last record per step. A step of 0
+// returns all records. The time of a downsampled record is the start of its step.
+func (th *TickHistory) Range(from, to time.Time, step time.Duration) []TickRecord {
+	th.lock.RLock()
+	defer th.lock.RUnlock()
+
+	var result []TickRecord
+	for _, record := range th.inRange(from, to) {
+		if step > 0 {
+			record.At = from.Add(record.At.Sub(from).Truncate(step))
+			if len(result) > 0 && result[len(result)-1].At.Equal(record.At) {
+				result[len(result)-1] = record
+				continue
+			}
+		}
+		result = append(result, record)
+	}
+	return result
+}
+
+// NodeTicks returns the ticks of the node from (inclusive) to (exclusive), downsampled like Range. Refreshes that did
+// not reach the node are left out. False is returned, if the node is not in any kept record.
+func (th *TickHistory) NodeTicks(address string, from, to time.Time, step time.Duration) ([]NodeTick, bool) {
+	th.lock.RLock()
+	known := slices.ContainsFunc(th.records, func(record TickRecord) bool {
+		_, ok := record.NodeTicks[address]
+		return ok
+	})
+	th.lock.RUnlock()
+	if !known {
+		return nil, false
+	}
+
+	result := make([]NodeTick, 0)
+	for _, record := range th.Range(from, to, 0) {
+		tick, ok := record.NodeTicks[address]
+		if !ok {
+			continue
+		}
+		point := NodeTick{At: record.At, Tick: tick, MaxTick: record.MaxTick}
+		if step > 0 {
+			point.At = from.Add(record.At.Sub(from).Truncate(step))
+			if len(result) > 0 && result[len(result)-1].At.Equal(point.At) {
+				result[len(result)-1] = point
+				continue
+			}
+		}
+		result = append(result, point)
+	}
+	return result, true
+}
+
+func (th *TickHistory) inRange(from, to time.Time) []TickRecord {
+	compare := func(record TickRecord, at time.Time) int {
+		return record.At.Compare(at)
+	}
+	start, _ := slices.BinarySearchFunc(th.records, from, compare)
+	end, _ := slices.BinarySearchFunc(th.records, to, compare)
+	if end < start {
+		return nil
+	}
+	return th.records[start:end]
+}
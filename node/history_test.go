@@ -0,0 +1,151 @@
+package node
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var historyStart = time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+func recordTicks(t *testing.T, history *TickHistory, count int) {
+	for i := 0; i < count; i++ {
+		err := history.Record(TickRecord{
+			At:            historyStart.Add(time.Duration(i) * 10 * time.Second),
+			MaxTick:       uint32(1000 + i),
+			ReliableNodes: 2,
+			NodeTicks:     map[string]uint32{"1.2.3.4": uint32(1000 + i), "2.3.4.5": uint32(999 + i)},
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestTickHistory_Range(t *testing.T) {
+	history := NewTickHistory(0)
+	recordTicks(t, history, 12) // 2 minutes
+
+	records := history.Range(historyStart.Add(30*time.Second), historyStart.Add(time.Minute), 0)
+	require.Len(t, records, 3)
+	assert.Equal(t, uint32(1003), records[0].MaxTick)
+	assert.Equal(t, uint32(1005), records[2].MaxTick)
+
+	records = history.Range(historyStart, historyStart.Add(time.Hour), time.Minute)
+	require.Len(t, records, 2)
+	assert.Equal(t, historyStart, records[0].At)
+	assert.Equal(t, uint32(1005), records[0].MaxTick, "last record of the step")
+	assert.Equal(t, historyStart.Add(time.Minute), records[1].At)
+	assert.Equal(t, uint32(1011), records[1].MaxTick)
+
+	assert.Empty(t, history.Range(historyStart.Add(time.Hour), historyStart, 0))
+}
+
+func TestTickHistory_NodeTicks(t *testing.T) {
+	history := NewTickHistory(0)
+	recordTicks(t, history, 6)
+	require.NoError(t, history.Record(TickRecord{At: historyStart.Add(time.Minute), MaxTick: 1006, NodeTicks: map[string]uint32{"1.2.3.4": 1006}}))
+
+	ticks, ok := history.NodeTicks("2.3.4.5", historyStart, historyStart.Add(time.Hour), 0)
+	require.True(t, ok)
+	require.Len(t, ticks, 6, "refresh without the node is left out")
+	assert.Equal(t, NodeTick{At: historyStart.Add(50 * time.Second), Tick: 1004, MaxTick: 1005}, ticks[5])
+
+	ticks, ok = history.NodeTicks("2.3.4.5", historyStart, historyStart.Add(time.Hour), 30*time.Second)
+	require.True(t, ok)
+	assert.Equal(t, []NodeTick{
+		{At: historyStart, Tick: 1001, MaxTick: 1002},
+		{At: historyStart.Add(30 * time.Second), Tick: 1004, MaxTick: 1005},
+	}, ticks)
+
+	_, ok = history.NodeTicks("6.6.6.6", historyStart, historyStart.Add(time.Hour), 0)
+	assert.False(t, ok)
+}
+
+func TestTickHistory_dropsRecordsAfterRetention(t *testing.T) {
+	history := NewTickHistory(time.Minute)
+	recordTicks(t, history, 12)
+
+	records := history.Range(time.Time{}, historyStart.Add(time.Hour), 0)
+	require.Len(t, records, 7)
+	assert.Equal(t, historyStart.Add(50*time.Second), records[0].At)
+}
+
+func TestTickHistory_persistsRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	history, err := OpenTickHistory(path, 0)
+	require.NoError(t, err)
+	recordTicks(t, history, 3)
+	require.NoError(t, history.Close())
+
+	// simulate a crash while writing
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"at":"2024-05-`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	reopened, err := OpenTickHistory(path, 0)
+	require.NoError(t, err)
+	defer reopened.Close()
+	records := reopened.Range(time.Time{}, historyStart.Add(time.Hour), 0)
+	require.Len(t, records, 3)
+	assert.Equal(t, uint32(1002), records[2].MaxTick)
+	assert.Equal(t, uint32(1001), records[2].NodeTicks["2.3.4.5"])
+}
+
+func TestTickHistory_compactsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	history, err := OpenTickHistory(path, 30*time.Second)
+	require.NoError(t, err)
+	defer history.Close()
+
+	recordTicks(t, history, 20)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Count(string(data), "\n")
+	assert.True(t, lines <= 2*len(history.Range(time.Time{}, historyStart.Add(time.Hour), 0))+1, "file not compacted: %d lines", lines)
+}
+
+func TestTickHistory_whenCompactionFails_thenKeepPersisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	history, err := OpenTickHistory(path, 0)
+	require.NoError(t, err)
+	defer history.Close()
+	recordTicks(t, history, 2)
+
+	history.path = filepath.Join(filepath.Dir(path), "missing", "history.jsonl")
+	assert.Error(t, history.compact())
+	history.path = path
+	require.NoError(t, history.Record(TickRecord{At: historyStart.Add(time.Minute), MaxTick: 1002}))
+
+	// the file could not be reopened after the last compaction
+	require.NoError(t, history.file.Close())
+	history.file = nil
+	require.NoError(t, history.Record(TickRecord{At: historyStart.Add(2 * time.Minute), MaxTick: 1003}))
+	require.NoError(t, history.Record(TickRecord{At: historyStart.Add(3 * time.Minute), MaxTick: 1004}))
+
+	records, err := readTickRecords(path)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, uint32(1004), records[4].MaxTick)
+}
+
+func TestContainer_Update_thenRecordHistory(t *testing.T) {
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+	container.History = NewTickHistory(time.Hour)
+
+	require.NoError(t, container.Update(context.Background()))
+	require.NoError(t, container.Update(context.Background()))
+
+	records := container.History.Range(time.Time{}, time.Now().Add(time.Minute), 0)
+	require.Len(t, records, 2)
+	assert.Equal(t, uint32(42), records[1].MaxTick)
+	assert.Equal(t, 2, records[1].ReliableNodes)
+	assert.Equal(t, map[string]uint32{"1.2.3.4": 42, "2.3.4.5": 42}, records[1].NodeTicks)
+}
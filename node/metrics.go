@@ -0,0 +1,133 @@
+package node
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "qubic_nodes"
+
+var (
+	maxTickGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "max_tick",
+		Help:      "Max tick agreed by the online nodes.",
+	})
+	degradedGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "degraded",
+		Help:      "1 if the max tick quorum was not reached in the last refresh, otherwise 0.",
+	})
+	stalledGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "stalled",
+		Help:      "1 if the max tick did not advance for longer than the stall timeout, otherwise 0.",
+	})
+	tickRateGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "ticks_per_second",
+		Help:      "Max tick progression averaged over the last refreshes.",
+	})
+	lastTickAdvanceGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_tick_advance_timestamp_seconds",
+		Help:      "Unix time of the last max tick advance.",
+	})
+	nodeCountGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "nodes",
+		Help:      "Number of nodes by state (configured, known, online, reliable, outlier).",
+	}, []string{"state"})
+	nodeLastTickGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_last_tick",
+		Help:      "Last tick reported by an online node.",
+	}, []string{"address"})
+	nodeLagGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_lag_ticks",
+		Help:      "Number of ticks an online node is behind the max tick.",
+	}, []string{"address"})
+	refreshDurationHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "refresh_duration_seconds",
+		Help:      "Duration of a node refresh.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+	})
+	dialErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dial_errors_total",
+		Help:      "Number of failed node polls by error type (dial_timeout, connection_refused, handshake_failure, response_timeout, protocol_error, other).",
+	}, []string{"type"})
+	failingNodesGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "failing_nodes",
+		Help:      "Number of known nodes that failed the last poll by error type.",
+	}, []string{"type"})
+	discoveredPeersCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "discovery_peers_added_total",
+		Help:      "Number of peers added by peer discovery.",
+	})
+	removedPeersCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "discovery_peers_removed_total",
+		Help:      "Number of unhealthy peers removed by peer discovery.",
+	})
+	dialQueueGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "dial_queue_depth",
+		Help:      "Number of dials waiting for a free worker.",
+	})
+	dialsInFlightGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "dials_in_flight",
+		Help:      "Number of dials currently running.",
+	})
+	dialTimeoutsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dial_queue_timeouts_total",
+		Help:      "Number of dials skipped because the refresh deadline was exceeded while queued.",
+	})
+)
+
+func recordRefreshMetrics(c *Container, onlineNodes []*Node, reliableNodes []*Node, outlierNodes []*TickOutlier, maxTick uint32, degraded bool) {
+	maxTickGauge.Set(float64(maxTick))
+	if degraded {
+		degradedGauge.Set(1)
+	} else {
+		degradedGauge.Set(0)
+	}
+
+	nodeCountGauge.WithLabelValues("configured").Set(float64(c.GetNumberOfConfiguredNodes()))
+	nodeCountGauge.WithLabelValues("known").Set(float64(c.GetNumberOfKnownNodes()))
+	nodeCountGauge.WithLabelValues("online").Set(float64(len(onlineNodes)))
+	nodeCountGauge.WithLabelValues("reliable").Set(float64(len(reliableNodes)))
+	nodeCountGauge.WithLabelValues("outlier").Set(float64(len(outlierNodes)))
+
+	// reset to drop nodes that are not online anymore
+	nodeLastTickGauge.Reset()
+	nodeLagGauge.Reset()
+	for _, node := range onlineNodes {
+		nodeLastTickGauge.WithLabelValues(node.Address).Set(float64(node.LastTick))
+		nodeLagGauge.WithLabelValues(node.Address).Set(float64(node.TickLag(maxTick)))
+	}
+}
+
+func recordStallMetrics(stall StallStatus) {
+	if stall.Stalled {
+		stalledGauge.Set(1)
+	} else {
+		stalledGauge.Set(0)
+	}
+	lastTickAdvanceGauge.Set(float64(stall.Since.Unix()))
+}
+
+func recordNodeErrorMetrics(nodes []NodeInfo) {
+	failingNodesGauge.Reset()
+	for _, info := range nodes {
+		if info.State == NodeStateOffline && info.Health.LastErrorType != "" {
+			failingNodesGauge.WithLabelValues(info.Health.LastErrorType).Inc()
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package node
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRecordRefreshMetrics(t *testing.T) {
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	container := &Container{PeerManager: peerManager}
+	online := []*Node{{Address: "1.2.3.4", LastTick: 100}, {Address: "2.3.4.5", LastTick: 90}}
+
+	recordRefreshMetrics(container, online, online[:1], nil, 100, true)
+
+	assert.Equal(t, 100.0, testutil.ToFloat64(maxTickGauge))
+	assert.Equal(t, 1.0, testutil.ToFloat64(degradedGauge))
+	assert.Equal(t, 2.0, testutil.ToFloat64(nodeCountGauge.WithLabelValues("configured")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(nodeCountGauge.WithLabelValues("online")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(nodeCountGauge.WithLabelValues("reliable")))
+	assert.Equal(t, 10.0, testutil.ToFloat64(nodeLagGauge.WithLabelValues("2.3.4.5")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(nodeLagGauge.WithLabelValues("1.2.3.4")))
+
+	// nodes that are not online anymore are dropped
+	recordRefreshMetrics(container, online[:1], online[:1], nil, 101, false)
+	assert.Equal(t, 1, testutil.CollectAndCount(nodeLastTickGauge))
+	assert.Equal(t, 0.0, testutil.ToFloat64(degradedGauge))
+}
+
+func TestRecordNodeErrorMetrics(t *testing.T) {
+	nodes := []NodeInfo{
+		{State: NodeStateOffline, Health: HealthStats{LastErrorType: ErrorTypeConnectionRefused}},
+		{State: NodeStateOffline, Health: HealthStats{LastErrorType: ErrorTypeConnectionRefused}},
+		{State: NodeStateOffline, Health: HealthStats{LastErrorType: ErrorTypeDialTimeout}},
+		{State: NodeStateOffline},
+		{State: NodeStateReliable, Health: HealthStats{LastErrorType: ErrorTypeProtocolError}},
+	}
+
+	recordNodeErrorMetrics(nodes)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(failingNodesGauge))
+	assert.Equal(t, 2.0, testutil.ToFloat64(failingNodesGauge.WithLabelValues(ErrorTypeConnectionRefused)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(failingNodesGauge.WithLabelValues(ErrorTypeDialTimeout)))
+}
@@ -1,11 +1,13 @@
 package node
 
 import (
+	"cmp"
 	"context"
 	"github.com/pkg/errors"
 	qubic "github.com/qubic/go-node-connector"
 	"github.com/qubic/go-node-connector/types"
 	"log"
+	"slices"
 	"time"
 )
 
@@ -14,57 +16,122 @@ type Node struct {
 	Port              string
 	Peers             types.PublicPeers
 	LastTick          uint32
+	Epoch             uint16
+	InitialTick       uint32
+	TickDuration      uint16
+	ConnectLatency    time.Duration
+	TickInfoLatency   time.Duration
 	LastUpdate        int64
 	LastUpdateSuccess bool
 }
 
-func NewNode(ip string, port string, connectionTimeout time.Duration) (*Node, error) {
+func NewNode(ctx context.Context, ip string, port string, connectionTimeout time.Duration) (*Node, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	ctx, cancel := context.WithTimeout(ctx, connectionTimeout)
 	defer cancel()
-	client, err := qubic.NewClient(ctx, ip, port)
+	start := time.Now()
+	client, err := newClient(ctx, ip, port)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating node connection")
+		return nil, newConnectError(err)
 	}
 	defer client.Close()
 
-	tickInfo, err := client.GetTickInfo(ctx)
+	node := Node{
+		Address:        ip,
+		Port:           port,
+		Peers:          client.Peers,
+		ConnectLatency: time.Since(start),
+	}
+	err = node.Update(ctx, client)
 	if err != nil {
-		return nil, errors.Wrap(err, "getting tick info from node")
+		return nil, err
 	}
 
-	log.Printf("Found online node: %s - %d\n", ip, tickInfo.Tick)
+	log.Printf("Found online node: %s - %d\n", ip, node.LastTick)
 
-	node := Node{
-		Address:           ip,
-		Port:              port,
-		Peers:             client.Peers,
-		LastTick:          tickInfo.Tick,
-		LastUpdate:        time.Now().UTC().Unix(),
-		LastUpdateSuccess: true,
-	}
 	return &node, nil
 }
 
-func (n *Node) Update(connectionTimeout time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
-	defer cancel()
-	client, err := qubic.NewClient(ctx, n.Address, n.Port)
-	if err != nil {
-		return errors.Wrap(err, "creating node connection")
+// newClient connects to the node. The client only applies the deadline of the context, so a canceled context
+// returns early and the connection is closed, once it is established.
+func newClient(ctx context.Context, ip string, port string) (*qubic.Client, error) {
+	type result struct {
+		client *qubic.Client
+		err    error
 	}
-	defer client.Close()
+	results := make(chan result, 1)
+	go func() {
+		client, err := qubic.NewClient(ctx, ip, port)
+		results <- result{client: client, err: err}
+	}()
 
+	select {
+	case r := <-results:
+		return r.client, r.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			r := <-results // the client times out by itself
+			return r.client, r.err
+		}
+		go func() {
+			if r := <-results; r.client != nil {
+				_ = r.client.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// Update refreshes the tick information of the node using the given connection and measures the round-trip latency.
+// The connection is closed, if the context is done during the exchange.
+func (n *Node) Update(ctx context.Context, client *qubic.Client) error {
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.Close()
+	})
+	start := time.Now()
 	tickInfo, err := client.GetTickInfo(ctx)
+	if !stop() {
+		err = ctx.Err() // the connection got closed
+	}
 	if err != nil {
 		n.LastUpdateSuccess = false
-		return errors.Wrap(err, "getting tick info from node")
+		return newTickInfoError(err)
 	}
 
-	n.Peers = client.Peers
 	n.LastTick = tickInfo.Tick
+	n.Epoch = tickInfo.Epoch
+	n.InitialTick = tickInfo.InitialTick
+	n.TickDuration = tickInfo.TickDuration
+	n.TickInfoLatency = time.Since(start)
 	n.LastUpdate = time.Now().UTC().Unix()
 	n.LastUpdateSuccess = true
 
 	return nil
 }
+
+// TickLag returns the number of ticks the node is behind the given max tick.
+func (n *Node) TickLag(maxTick uint32) uint32 {
+	if n.LastTick >= maxTick {
+		return 0
+	}
+	return maxTick - n.LastTick
+}
+
+// SortByLatency returns a copy of the nodes ordered by tick info latency. Nodes without measured latency come last and
+// ties are broken by the lower address.
+func SortByLatency(nodes []*Node) []*Node {
+	sorted := slices.Clone(nodes)
+	slices.SortStableFunc(sorted, func(a, b *Node) int {
+		if (a.TickInfoLatency == 0) != (b.TickInfoLatency == 0) {
+			if a.TickInfoLatency == 0 {
+				return 1
+			}
+			return -1
+		}
+		if c := cmp.Compare(a.TickInfoLatency, b.TickInfoLatency); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Address, b.Address)
+	})
+	return sorted
+}
@@ -0,0 +1,78 @@
+package node
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"net"
+	"syscall"
+)
+
+const (
+	ErrorTypeDialTimeout       = "dial_timeout"
+	ErrorTypeConnectionRefused = "connection_refused"
+	ErrorTypeHandshakeFailure  = "handshake_failure"
+	ErrorTypeResponseTimeout   = "response_timeout"
+	ErrorTypeProtocolError     = "protocol_error"
+	ErrorTypeOther             = "other"
+)
+
+// NodeError is a failed poll of a node together with the classified cause.
+type NodeError struct {
+	Type string
+	Err  error
+}
+
+func (e *NodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// newConnectError classifies errors of creating a connection. The connection is established with a TCP dial followed
+// by a peer exchange, errors after the dial are handshake failures.
+func newConnectError(err error) error {
+	errorType := ErrorTypeHandshakeFailure
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		switch {
+		case opErr.Timeout():
+			errorType = ErrorTypeDialTimeout
+		case errors.Is(err, syscall.ECONNREFUSED):
+			errorType = ErrorTypeConnectionRefused
+		default:
+			errorType = ErrorTypeOther
+		}
+	}
+	return &NodeError{Type: errorType, Err: errors.Wrap(err, "creating node connection")}
+}
+
+// newTickInfoError classifies errors of requesting the tick info on an established connection.
+func newTickInfoError(err error) error {
+	errorType := ErrorTypeProtocolError
+	if isTimeout(err) {
+		errorType = ErrorTypeResponseTimeout
+	}
+	return &NodeError{Type: errorType, Err: errors.Wrap(err, "getting tick info from node")}
+}
+
+// classifyError returns the type of node errors. Other errors are classified by their cause.
+func classifyError(err error) string {
+	var nodeErr *NodeError
+	switch {
+	case errors.As(err, &nodeErr):
+		return nodeErr.Type
+	case isTimeout(err):
+		return ErrorTypeDialTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorTypeConnectionRefused
+	default:
+		return ErrorTypeOther
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,40 @@
+package node
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewConnectError(t *testing.T) {
+	_, err := net.DialTimeout("tcp", "10.255.255.1:21841", time.Nanosecond)
+	assert.Equal(t, ErrorTypeDialTimeout, classifyError(newConnectError(err)))
+
+	unresolved := &net.OpError{Op: "dial", Err: &net.DNSError{}}
+	assert.Equal(t, ErrorTypeOther, classifyError(newConnectError(unresolved)))
+
+	refused := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	assert.Equal(t, ErrorTypeConnectionRefused, classifyError(newConnectError(refused)))
+
+	handshake := errors.Wrap(&net.OpError{Op: "read", Err: syscall.ECONNRESET}, "getting Peers")
+	assert.Equal(t, ErrorTypeHandshakeFailure, classifyError(newConnectError(handshake)))
+	assert.Equal(t, "creating node connection: getting Peers: read: connection reset by peer", newConnectError(handshake).Error())
+}
+
+func TestNewTickInfoError(t *testing.T) {
+	assert.Equal(t, ErrorTypeResponseTimeout, classifyError(newTickInfoError(errors.Wrap(context.DeadlineExceeded, "reading"))))
+	assert.Equal(t, ErrorTypeProtocolError, classifyError(newTickInfoError(errors.Wrap(io.ErrUnexpectedEOF, "reading"))))
+}
+
+func TestClassifyError(t *testing.T) {
+	wrapped := errors.Wrap(&NodeError{Type: ErrorTypeProtocolError, Err: errors.New("malformed")}, "polling")
+	assert.Equal(t, ErrorTypeProtocolError, classifyError(wrapped))
+	assert.Equal(t, ErrorTypeDialTimeout, classifyError(errors.Wrap(context.DeadlineExceeded, "getting tick info")))
+	assert.Equal(t, ErrorTypeConnectionRefused, classifyError(errors.Wrap(syscall.ECONNREFUSED, "creating node connection")))
+	assert.Equal(t, ErrorTypeOther, classifyError(errors.New("unexpected")))
+}
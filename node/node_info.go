@@ -0,0 +1,122 @@
+package node
+
+import (
+	"cmp"
+	"slices"
+)
+
+const (
+	// NodeStateReliable means the node is online and within the reliable tick range of the max tick.
+	NodeStateReliable = "reliable"
+	// NodeStateOnline means the node is online, but lagging behind the reliable tick range.
+	NodeStateOnline = "online"
+	// NodeStateExcluded means the node is online, but rejected as tick outlier or for being on a previous epoch.
+	NodeStateExcluded = "excluded"
+	// NodeStateOffline means the node is known, but did not answer in the last refresh.
+	NodeStateOffline = "offline"
+	// NodeStateSkipped means the node is known, but was not polled in the last refresh, because the refresh deadline
+	// passed while it waited for a dial slot.
+	NodeStateSkipped = "skipped"
+)
+
+const (
+	NodeSourceConfigured = "configured"
+	NodeSourceDiscovered = "discovered"
+)
+
+// NodeInfo combines the state of a known node in the last refresh with its health history. For offline and skipped
+// nodes only the address of the node is set.
+type NodeInfo struct {
+	Node      Node
+	State     string
+	Source    string
+	Lag       uint32
+	Reason    string
+	Health    HealthStats
+	HasHealth bool
+}
+
+// GetNodes returns all known and online nodes ordered by address.
+func (c *Container) GetNodes() []NodeInfo {
+	return c.nodeInfos(c.GetSnapshot())
+}
+
+func (c *Container) nodeInfos(snapshot *Snapshot) []NodeInfo {
+	maxTick := snapshot.MaxTick
+	reliableNodes := addressesOf(snapshot.ReliableNodes)
+
+	configured := c.PeerManager.GetConfiguredPeers()
+	infos := make(map[string]*NodeInfo)
+	for _, address := range c.PeerManager.GetKnownPeers() {
+		infos[address] = &NodeInfo{Node: Node{Address: address}, State: NodeStateOffline}
+	}
+	for _, address := range c.PeerManager.GetSkippedPeers() {
+		if info, ok := infos[address]; ok {
+			info.State = NodeStateSkipped
+		}
+	}
+	for _, node := range snapshot.OnlineNodes {
+		infos[node.Address] = &NodeInfo{
+			Node:  *node,
+			State: NodeStateOnline,
+			Lag:   node.TickLag(maxTick),
+		}
+		if slices.Contains(reliableNodes, node.Address) {
+			infos[node.Address].State = NodeStateReliable
+		}
+	}
+	for _, outlier := range snapshot.OutlierNodes {
+		if info, ok := infos[outlier.Node.Address]; ok {
+			info.State = NodeStateExcluded
+			info.Reason = outlier.Reason
+		}
+	}
+
+	result := make([]NodeInfo, 0, len(infos))
+	for address, info := range infos {
+		info.Source = NodeSourceDiscovered
+		if slices.Contains(configured, address) {
+			info.Source = NodeSourceConfigured
+		}
+		info.Health, info.HasHealth = c.PeerManager.GetNodeHealth(address)
+		result = append(result, *info)
+	}
+	slices.SortFunc(result, func(a, b NodeInfo) int {
+		return cmp.Compare(a.Node.Address, b.Node.Address)
+	})
+	return result
+}
+
+// NodeDetail extends the node info with the recent polls and the online nodes that report the node as peer.
+type NodeDetail struct {
+	NodeInfo
+	MaxTick    uint32
+	Polls      []PollSample
+	ReportedBy []string
+}
+
+// GetNode returns the details of a known or online node.
+func (c *Container) GetNode(address string) (NodeDetail, bool) {
+	snapshot := c.GetSnapshot()
+	nodes := c.nodeInfos(snapshot)
+	index := slices.IndexFunc(nodes, func(info NodeInfo) bool {
+		return info.Node.Address == address
+	})
+	if index < 0 {
+		return NodeDetail{}, false
+	}
+
+	detail := NodeDetail{
+		NodeInfo:   nodes[index],
+		MaxTick:    snapshot.MaxTick,
+		Polls:      c.PeerManager.GetNodePolls(address),
+		ReportedBy: []string{},
+	}
+	for _, node := range snapshot.OnlineNodes {
+		if slices.Contains(node.Peers, address) {
+			detail.ReportedBy = append(detail.ReportedBy, node.Address)
+		}
+	}
+	slices.Sort(detail.ReportedBy)
+	return detail, true
+}
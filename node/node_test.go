@@ -0,0 +1,20 @@
+package node
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSortByLatency(t *testing.T) {
+	unmeasured := &Node{Address: "1.2.3.4"}
+	slow := &Node{Address: "2.3.4.5", TickInfoLatency: 20 * time.Millisecond}
+	fast := &Node{Address: "3.4.5.6", TickInfoLatency: 5 * time.Millisecond}
+	fastTie := &Node{Address: "0.1.2.3", TickInfoLatency: 5 * time.Millisecond}
+	nodes := []*Node{unmeasured, slow, fast, fastTie}
+
+	sorted := SortByLatency(nodes)
+
+	assert.Equal(t, []*Node{fastTie, fast, slow, unmeasured}, sorted)
+	assert.Equal(t, []*Node{unmeasured, slow, fast, fastTie}, nodes)
+}
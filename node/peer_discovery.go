@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"log"
 	"slices"
 	"strings"
@@ -37,12 +38,15 @@ func (pl *UpdatedPeerList) addIfNew(host string) bool {
 }
 
 type PeerDiscovery interface {
-	FindNewPeers(currentNodes []*Node, currentAddresses []string) []*Node
-	CleanupPeers(currentNodes []*Node, currentAddresses []string) []string
+	FindNewPeers(ctx context.Context, currentNodes []*Node, currentAddresses []string) []*Node
+	// CleanupPeers returns the unhealthy peers. Skipped peers were not polled and are never unhealthy.
+	CleanupPeers(currentNodes []*Node, currentAddresses []string, skippedAddresses []string) []string
 }
 
 type PublicPeerDiscovery struct {
 	createNodeFunction CreateNode
+	workerPool         *WorkerPool
+	lookupDeadline     time.Duration
 	excludedPeers      []string
 	cleanInterval      time.Duration
 	latestCleanup      time.Time
@@ -51,22 +55,22 @@ type PublicPeerDiscovery struct {
 
 type NoPeerDiscovery struct{}
 
-func (npd *NoPeerDiscovery) FindNewPeers(_ []*Node, _ []string) []*Node {
+func (npd *NoPeerDiscovery) FindNewPeers(_ context.Context, _ []*Node, _ []string) []*Node {
 	return []*Node{}
 }
 
-func (npd *NoPeerDiscovery) CleanupPeers(_ []*Node, _ []string) []string {
+func (npd *NoPeerDiscovery) CleanupPeers(_ []*Node, _ []string, _ []string) []string {
 	return []string{}
 }
 
-func NewPublicPeerDiscovery(port string, connectionTimeout time.Duration, excludedPeers []string, cleanInterval time.Duration) *PublicPeerDiscovery {
-	createNodeFunc := func(host string) (*Node, error) {
-		return NewNode(host, port, connectionTimeout)
+func NewPublicPeerDiscovery(port string, connectionTimeout time.Duration, excludedPeers []string, cleanInterval time.Duration, workerPool *WorkerPool, lookupDeadline time.Duration) *PublicPeerDiscovery {
+	createNodeFunc := func(ctx context.Context, host string) (*Node, error) {
+		return NewNode(ctx, host, port, connectionTimeout)
 	}
-	return newPublicPeerDiscovery(createNodeFunc, excludedPeers, cleanInterval)
+	return newPublicPeerDiscovery(createNodeFunc, excludedPeers, cleanInterval, workerPool, lookupDeadline)
 }
 
-func newPublicPeerDiscovery(createNodeFunc CreateNode, excludedPeers []string, cleanInterval time.Duration) *PublicPeerDiscovery {
+func newPublicPeerDiscovery(createNodeFunc CreateNode, excludedPeers []string, cleanInterval time.Duration, workerPool *WorkerPool, lookupDeadline time.Duration) *PublicPeerDiscovery {
 	// trim host names
 	var trimmed []string
 	for _, peer := range excludedPeers {
@@ -74,6 +78,8 @@ func newPublicPeerDiscovery(createNodeFunc CreateNode, excludedPeers []string, c
 	}
 	return &PublicPeerDiscovery{
 		createNodeFunction: createNodeFunc,
+		workerPool:         workerPool,
+		lookupDeadline:     lookupDeadline,
 		excludedPeers:      trimmed,
 		cleanInterval:      cleanInterval,
 		latestCleanup:      time.Now(),
@@ -81,11 +87,17 @@ func newPublicPeerDiscovery(createNodeFunc CreateNode, excludedPeers []string, c
 	}
 }
 
-func (ppd *PublicPeerDiscovery) CleanupPeers(nodes []*Node, addresses []string) []string {
+func (ppd *PublicPeerDiscovery) CleanupPeers(nodes []*Node, addresses []string, skippedAddresses []string) []string {
+	ppd.lock.Lock()
+	defer ppd.lock.Unlock()
+
 	var unhealthyPeers []string
 	// clean, if clean interval is over, and we have at least one healthy node (to retrieve more peers)
 	if len(nodes) >= 1 && ppd.latestCleanup.Add(ppd.cleanInterval).Before(time.Now()) {
 		for _, address := range addresses {
+			if slices.Contains(skippedAddresses, address) {
+				continue
+			}
 			if !slices.ContainsFunc(nodes, func(node *Node) bool { return node.Address == address }) {
 				log.Printf("Unhealthy peer: [%s].", address)
 				unhealthyPeers = append(unhealthyPeers, address)
@@ -97,7 +109,7 @@ func (ppd *PublicPeerDiscovery) CleanupPeers(nodes []*Node, addresses []string)
 	return unhealthyPeers
 }
 
-func (ppd *PublicPeerDiscovery) FindNewPeers(nodes []*Node, addresses []string) []*Node {
+func (ppd *PublicPeerDiscovery) FindNewPeers(ctx context.Context, nodes []*Node, addresses []string) []*Node {
 	peerCopy := make([]string, len(addresses))
 	copy(peerCopy, addresses) // might get changed
 	peers := &UpdatedPeerList{
@@ -106,10 +118,13 @@ func (ppd *PublicPeerDiscovery) FindNewPeers(nodes []*Node, addresses []string)
 		newPeers:      []string{},
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, ppd.lookupDeadline)
+	defer cancel()
+
 	var waitGroup sync.WaitGroup
 	nodesChannel := make(chan *Node, maxNewPeersPerUpdate)
 	for _, node := range nodes {
-		ppd.lookupPeers(node.Peers, peers, nodesChannel, &waitGroup)
+		ppd.lookupPeers(ctx, node.Peers, peers, nodesChannel, &waitGroup)
 	}
 	waitGroup.Wait()
 	close(nodesChannel)
@@ -124,22 +139,26 @@ func (ppd *PublicPeerDiscovery) FindNewPeers(nodes []*Node, addresses []string)
 }
 
 // recursive
-func (ppd *PublicPeerDiscovery) lookupPeers(hosts []string, peers *UpdatedPeerList, channel chan *Node, waitGroup *sync.WaitGroup) {
+func (ppd *PublicPeerDiscovery) lookupPeers(ctx context.Context, hosts []string, peers *UpdatedPeerList, channel chan *Node, waitGroup *sync.WaitGroup) {
 	for _, host := range hosts {
 		// abort if channel is filled with next peer
 		if len(channel) < maxNewPeersPerUpdate-2 && peers.addIfNew(host) {
 			waitGroup.Add(1)
-			go ppd.lookupPeer(host, peers, channel, waitGroup)
+			go ppd.lookupPeer(ctx, host, peers, channel, waitGroup)
 		}
 	}
 }
 
 // recursive
-func (ppd *PublicPeerDiscovery) lookupPeer(host string, peers *UpdatedPeerList, channel chan *Node, waitGroup *sync.WaitGroup) {
+func (ppd *PublicPeerDiscovery) lookupPeer(ctx context.Context, host string, peers *UpdatedPeerList, channel chan *Node, waitGroup *sync.WaitGroup) {
 	defer waitGroup.Done()
-	node, err := ppd.createNodeFunction(host)
-	if err == nil {
+	var node *Node
+	var err error
+	ran := ppd.workerPool.Run(ctx, func() {
+		node, err = ppd.createNodeFunction(ctx, host)
+	})
+	if ran && err == nil {
 		channel <- node
-		ppd.lookupPeers(node.Peers, peers, channel, waitGroup)
+		ppd.lookupPeers(ctx, node.Peers, peers, channel, waitGroup)
 	}
 }
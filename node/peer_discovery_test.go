@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"sync"
@@ -10,12 +11,12 @@ import (
 
 func TestNoPeerDiscovery_UpdatePeers(t *testing.T) {
 	discovery := NoPeerDiscovery{}
-	assert.Empty(t, discovery.FindNewPeers([]*Node{}, []string{"1.2.3.4", "2.3.4.5"}))
+	assert.Empty(t, discovery.FindNewPeers(context.Background(), []*Node{}, []string{"1.2.3.4", "2.3.4.5"}))
 }
 
 func TestNoPeerDiscovery_CleanupPeers(t *testing.T) {
 	discovery := NoPeerDiscovery{}
-	assert.Empty(t, discovery.CleanupPeers([]*Node{}, []string{"1.2.3.4"}))
+	assert.Empty(t, discovery.CleanupPeers([]*Node{}, []string{"1.2.3.4"}, nil))
 }
 
 func TestPeerList_Contains(t *testing.T) {
@@ -62,7 +63,7 @@ func TestPeerList_IsAcceptedHost(t *testing.T) {
 }
 
 func TestPublicPeerDiscovery_UpdatePeers(t *testing.T) {
-	createNodeFunc := func(host string) (*Node, error) {
+	createNodeFunc := func(_ context.Context, host string) (*Node, error) {
 		if host == "6.6.6.6" {
 			return nil, errors.Errorf("Error creating node [%s].", host)
 		} else {
@@ -71,9 +72,9 @@ func TestPublicPeerDiscovery_UpdatePeers(t *testing.T) {
 				nil
 		}
 	}
-	discovery := newPublicPeerDiscovery(createNodeFunc, []string{}, time.Hour)
+	discovery := newPublicPeerDiscovery(createNodeFunc, []string{}, time.Hour, NewWorkerPool(10), time.Second)
 
-	discoveredPeers := discovery.FindNewPeers([]*Node{
+	discoveredPeers := discovery.FindNewPeers(context.Background(), []*Node{
 		createTestNodeWithPeers("1.2.3.4",
 			[]string{"2.3.4.5", "3.4.5.6", "4.5.6.7", "5.6.7.8"}), //  3 new peers ("3.4.5.6", "4.5.6.7", "5.6.7.8")
 	}, []string{"1.2.3.4", "2.3.4.5"})
@@ -88,12 +89,12 @@ func TestPublicPeerDiscovery_UpdatePeers(t *testing.T) {
 }
 
 func TestPublicPeerDiscovery_ExcludePeers(t *testing.T) {
-	createNodeFunc := func(host string) (*Node, error) {
+	createNodeFunc := func(_ context.Context, host string) (*Node, error) {
 		return createTestNodeWithPeers(host, []string{"1.2.3.4", "6.6.6.6"}), nil // 6.6.6.6 excluded
 	}
-	discovery := newPublicPeerDiscovery(createNodeFunc, []string{" 6.6.6.6"}, time.Hour)
+	discovery := newPublicPeerDiscovery(createNodeFunc, []string{" 6.6.6.6"}, time.Hour, NewWorkerPool(10), time.Second)
 
-	discoveredPeers := discovery.FindNewPeers([]*Node{
+	discoveredPeers := discovery.FindNewPeers(context.Background(), []*Node{
 		createTestNodeWithPeers("1.2.3.4", []string{"2.3.4.5", "3.4.5.6"}), // 3.4.5.6 new peer
 	}, []string{"1.2.3.4", "2.3.4.5"})
 
@@ -103,29 +104,34 @@ func TestPublicPeerDiscovery_ExcludePeers(t *testing.T) {
 }
 
 func TestPublicPeerDiscovery_CleanupPeers(t *testing.T) {
-	createNodeFunc := func(host string) (*Node, error) {
+	createNodeFunc := func(_ context.Context, host string) (*Node, error) {
 		return nil, nil
 	}
-	discovery := newPublicPeerDiscovery(createNodeFunc, []string{}, 5*time.Millisecond)
+	discovery := newPublicPeerDiscovery(createNodeFunc, []string{}, 5*time.Millisecond, NewWorkerPool(10), time.Second)
 
 	time.Sleep(5 * time.Millisecond)
 	// no clean up as there is no healthy node
-	unhealthy := discovery.CleanupPeers([]*Node{}, []string{"2.3.4.5", "3.4.5.6"})
+	unhealthy := discovery.CleanupPeers([]*Node{}, []string{"2.3.4.5", "3.4.5.6"}, nil)
 	assert.Len(t, unhealthy, 0)
 
 	time.Sleep(5 * time.Millisecond)
 	// clean up both
-	unhealthy = discovery.CleanupPeers([]*Node{createTestNode("1.2.3.4")}, []string{"2.3.4.5", "3.4.5.6"})
+	unhealthy = discovery.CleanupPeers([]*Node{createTestNode("1.2.3.4")}, []string{"2.3.4.5", "3.4.5.6"}, nil)
 	assert.Len(t, unhealthy, 2)
 	assert.Contains(t, unhealthy, "2.3.4.5")
 	assert.Contains(t, unhealthy, "3.4.5.6")
 
 	time.Sleep(5 * time.Millisecond)
 	// clean up one
-	unhealthy = discovery.CleanupPeers([]*Node{createTestNode("2.3.4.5")}, []string{"2.3.4.5", "3.4.5.6"})
+	unhealthy = discovery.CleanupPeers([]*Node{createTestNode("2.3.4.5")}, []string{"2.3.4.5", "3.4.5.6"}, nil)
 	assert.Len(t, unhealthy, 1)
 	assert.Contains(t, unhealthy, "3.4.5.6")
 
+	time.Sleep(5 * time.Millisecond)
+	// keep skipped peers
+	unhealthy = discovery.CleanupPeers([]*Node{createTestNode("2.3.4.5")}, []string{"2.3.4.5", "3.4.5.6"}, []string{"3.4.5.6"})
+	assert.Len(t, unhealthy, 0)
+
 }
 
 func getHosts(discoveredPeers []*Node) []string {
@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"log"
 	"slices"
 	"strings"
@@ -10,57 +11,87 @@ import (
 
 type PeerManager struct {
 	configuredPeers    []string
-	currentPeers       []string
+	currentPeers       *peerRegistry
+	peerUpdateLock     sync.Mutex
 	peerDiscovery      PeerDiscovery
 	createNodeFunction CreateNode
+	connectionPool     *ConnectionPool
+	workerPool         *WorkerPool
+	refreshDeadline    time.Duration
+	health             *HealthTracker
+	skippedPeers       *peerRegistry
 }
 
-type CreateNode func(host string) (*Node, error)
+type CreateNode func(ctx context.Context, host string) (*Node, error)
 
-func NewPeerManager(addresses []string, peerDiscovery PeerDiscovery, port string, connectionTimeout time.Duration) *PeerManager {
-	crateNodeFunc := func(host string) (*Node, error) {
-		return NewNode(host, port, connectionTimeout)
-	}
-	return newPeerManagerWithCreateNodeFunction(addresses, peerDiscovery, crateNodeFunc)
+func NewPeerManager(addresses []string, peerDiscovery PeerDiscovery, connectionPool *ConnectionPool, workerPool *WorkerPool, refreshDeadline time.Duration) *PeerManager {
+	peerManager := newPeerManagerWithCreateNodeFunction(addresses, peerDiscovery, connectionPool.Poll, workerPool, refreshDeadline)
+	peerManager.connectionPool = connectionPool
+	return peerManager
 }
 
 // mainly for testing to inject custom node creation code
-func newPeerManagerWithCreateNodeFunction(addresses []string, peerDiscovery PeerDiscovery, createNodeFunction CreateNode) *PeerManager {
+func newPeerManagerWithCreateNodeFunction(addresses []string, peerDiscovery PeerDiscovery, createNodeFunction CreateNode, workerPool *WorkerPool, refreshDeadline time.Duration) *PeerManager {
 	// trim host names
 	var trimmed []string
 	for _, peer := range addresses {
 		trimmed = append(trimmed, strings.TrimSpace(peer))
 	}
-	configuredPeers := make([]string, len(trimmed))
-	copy(configuredPeers, trimmed) // assure that they are not changed by changing current peers
 	peerManager := PeerManager{
-		configuredPeers:    configuredPeers,
-		currentPeers:       trimmed,
+		configuredPeers:    trimmed,
+		currentPeers:       newPeerRegistry(trimmed),
 		createNodeFunction: createNodeFunction,
 		peerDiscovery:      peerDiscovery,
+		workerPool:         workerPool,
+		refreshDeadline:    refreshDeadline,
+		health:             NewHealthTracker(),
+		skippedPeers:       newPeerRegistry(nil),
 	}
 	return &peerManager
 }
 
-func (pm *PeerManager) UpdateNodes() []*Node {
-	onlineNodes := pm.fetchOnlineNodes()
-	go pm.updatePeers(onlineNodes)
+// UpdateNodes polls the current peers and returns the online nodes. The peers are updated in the background. Canceling
+// the context aborts in-flight polls and the following peer discovery. The caller owns the returned slice.
+func (pm *PeerManager) UpdateNodes(ctx context.Context) []*Node {
+	onlineNodes, skippedPeers := pm.fetchOnlineNodes(ctx)
+	pm.skippedPeers.set(skippedPeers)
+	go pm.updatePeers(ctx, slices.Clone(onlineNodes), skippedPeers)
 	return onlineNodes
 }
 
-func (pm *PeerManager) fetchOnlineNodes() []*Node {
+// fetchOnlineNodes returns the online nodes and the peers, that were not polled because the refresh deadline passed
+// while they waited for a dial slot.
+func (pm *PeerManager) fetchOnlineNodes(ctx context.Context) ([]*Node, []string) {
 
 	var waitGroup sync.WaitGroup
 
-	nodesChannel := make(chan *Node, len(pm.currentPeers))
-	for _, address := range pm.currentPeers {
+	ctx, cancel := context.WithTimeout(ctx, pm.refreshDeadline)
+	defer cancel()
+
+	peers := pm.currentPeers.list()
+	nodesChannel := make(chan *Node, len(peers))
+	skippedChannel := make(chan string, len(peers))
+	for _, address := range peers {
 		waitGroup.Add(1)
 		go func() {
 			defer waitGroup.Done()
 
-			node, err := pm.createNodeFunction(address)
+			var node *Node
+			var err error
+			ran := pm.workerPool.Run(ctx, func() {
+				start := time.Now()
+				node, err = pm.createNodeFunction(ctx, address)
+				pm.health.RecordPoll(address, err == nil, time.Since(start))
+			})
+			if !ran {
+				log.Printf("Skipped node [%s]: refresh deadline exceeded.", address)
+				skippedChannel <- address
+				return
+			}
 			if err != nil {
 				log.Printf("Failed to create node: %v.", err)
+				pm.health.RecordError(address, err)
+				dialErrorsCounter.WithLabelValues(classifyError(err)).Inc()
 				nodesChannel <- nil
 				return
 			}
@@ -71,6 +102,7 @@ func (pm *PeerManager) fetchOnlineNodes() []*Node {
 
 	waitGroup.Wait()
 	close(nodesChannel)
+	close(skippedChannel)
 
 	var onlineNodes []*Node
 	for node := range nodesChannel {
@@ -78,7 +110,11 @@ func (pm *PeerManager) fetchOnlineNodes() []*Node {
 			onlineNodes = append(onlineNodes, node)
 		}
 	}
-	return onlineNodes
+	var skippedPeers []string
+	for address := range skippedChannel {
+		skippedPeers = append(skippedPeers, address)
+	}
+	return onlineNodes, skippedPeers
 }
 
 func (pm *PeerManager) GetNumberOfConfiguredNodes() int {
@@ -86,27 +122,74 @@ func (pm *PeerManager) GetNumberOfConfiguredNodes() int {
 }
 
 func (pm *PeerManager) GetNumberOfKnownNodes() int {
-	return len(pm.currentPeers)
+	return pm.currentPeers.size()
 }
 
-func (pm *PeerManager) updatePeers(nodes []*Node) {
+func (pm *PeerManager) GetConfiguredPeers() []string {
+	return slices.Clone(pm.configuredPeers)
+}
+
+// GetSkippedPeers returns the peers, that were not polled in the last refresh because the refresh deadline passed.
+func (pm *PeerManager) GetSkippedPeers() []string {
+	return pm.skippedPeers.list()
+}
 
-	unhealthyPeers := pm.peerDiscovery.CleanupPeers(nodes, pm.currentPeers)
+// GetKnownPeers returns the addresses of the configured and discovered peers.
+func (pm *PeerManager) GetKnownPeers() []string {
+	return pm.currentPeers.list()
+}
+
+// RestorePeers adds the persisted peers to the current peers and restores their health history.
+func (pm *PeerManager) RestorePeers(peers []StoredPeer) {
+	pm.health.Restore(peers)
+	for _, peer := range peers {
+		pm.currentPeers.add(peer.Address)
+	}
+}
+
+// ExportPeers returns the current peers and their health history for persisting them.
+func (pm *PeerManager) ExportPeers() []StoredPeer {
+	return pm.health.Export(pm.currentPeers.list())
+}
+
+func (pm *PeerManager) GetWorkerPoolStats() WorkerPoolStats {
+	return pm.workerPool.Stats()
+}
+
+func (pm *PeerManager) GetNodeHealth(address string) (HealthStats, bool) {
+	return pm.health.Stats(address)
+}
+
+func (pm *PeerManager) GetNodePolls(address string) []PollSample {
+	return pm.health.Polls(address)
+}
+
+// updatePeers removes unhealthy and adds newly discovered peers. Skipped peers were not polled and are kept. It is
+// skipped, if the previous update is still running.
+func (pm *PeerManager) updatePeers(ctx context.Context, nodes []*Node, skippedPeers []string) {
+	if !pm.peerUpdateLock.TryLock() {
+		log.Printf("Skipped peer update: previous update still running.")
+		return
+	}
+	defer pm.peerUpdateLock.Unlock()
+
+	unhealthyPeers := pm.peerDiscovery.CleanupPeers(nodes, pm.currentPeers.list(), skippedPeers)
 	for _, host := range unhealthyPeers {
-		if !slices.Contains(pm.configuredPeers, host) { // don't remove configured nodes
-			// delete unhealthy peer from current peer list
+		if !slices.Contains(pm.configuredPeers, host) && pm.currentPeers.remove(host) { // don't remove configured nodes
 			log.Printf("Remove peer: [%s].", host)
-			pm.currentPeers = slices.DeleteFunc(pm.currentPeers, func(currentHost string) bool {
-				return strings.TrimSpace(currentHost) == host
-			})
+			removedPeersCounter.Inc()
+			pm.health.Remove(host)
+			if pm.connectionPool != nil {
+				pm.connectionPool.Remove(host)
+			}
 		}
 	}
 
-	newPeers := pm.peerDiscovery.FindNewPeers(nodes, pm.currentPeers)
+	newPeers := pm.peerDiscovery.FindNewPeers(ctx, nodes, pm.currentPeers.list())
 	for _, newPeer := range newPeers {
-		if !slices.Contains(pm.currentPeers, newPeer.Address) {
+		if pm.currentPeers.add(newPeer.Address) {
 			log.Printf("Add peer: [%s].", newPeer.Address)
-			pm.currentPeers = append(pm.currentPeers, newPeer.Address)
+			discoveredPeersCounter.Inc()
 		}
 	}
 }
@@ -1,16 +1,21 @@
 package node
 
 import (
+	"context"
+	"fmt"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 var testTime = time.Now()
 
-func createTestNodes(host string) (*Node, error) {
+func createTestNodes(_ context.Context, host string) (*Node, error) {
 	if host == "6.6.6.6" {
 		return nil, errors.New("error creating test node")
 	} else {
@@ -20,15 +25,137 @@ func createTestNodes(host string) (*Node, error) {
 
 func TestPeerManager_UpdateNodes(t *testing.T) {
 	peerDiscovery := NoPeerDiscovery{}
-	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "6.6.6.6", "2.3.4.5"}, &peerDiscovery, createTestNodes)
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "6.6.6.6", "2.3.4.5"}, &peerDiscovery, createTestNodes, NewWorkerPool(10), time.Second)
 
-	nodes := peerManager.UpdateNodes()
+	nodes := peerManager.UpdateNodes(context.Background())
 
 	assert.Len(t, nodes, 2)
 	assert.Contains(t, nodes, createTestNode("1.2.3.4"))
 	assert.Contains(t, nodes, createTestNode("2.3.4.5"))
 }
 
+func TestPeerManager_UpdateNodes_skipsNodesAfterRefreshDeadline(t *testing.T) {
+	release := make(chan struct{})
+	blockingNode := func(_ context.Context, host string) (*Node, error) {
+		<-release // occupies the only dial slot
+		return createTestNode(host), nil
+	}
+	workerPool := NewWorkerPool(1)
+	discovery := &blockingPeerDiscovery{started: make(chan []string, 1), release: make(chan struct{})}
+	close(discovery.release)
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}, discovery, blockingNode, workerPool, time.Millisecond)
+
+	results := make(chan []*Node)
+	go func() {
+		results <- peerManager.UpdateNodes(context.Background())
+	}()
+	for workerPool.Stats().TimedOut < 2 { // wait until the queued nodes are skipped
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	nodes := <-results
+	<-discovery.started
+	peerManager.peerUpdateLock.Lock() // wait for the peer update
+	defer peerManager.peerUpdateLock.Unlock()
+
+	assert.Len(t, nodes, 1)
+	skipped := peerManager.GetSkippedPeers()
+	assert.Len(t, skipped, 2)
+	assert.NotContains(t, skipped, nodes[0].Address)
+	assert.Equal(t, uint64(2), workerPool.Stats().TimedOut)
+}
+
+func TestPeerManager_updatePeers_keepsSkippedPeers(t *testing.T) {
+	discovery := newPublicPeerDiscovery(createTestNodes, []string{}, 0, NewWorkerPool(10), time.Second)
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4"}, discovery, createTestNodes, NewWorkerPool(10), time.Second)
+	peerManager.currentPeers.add("2.3.4.5")
+	peerManager.currentPeers.add("6.6.6.6")
+	time.Sleep(time.Millisecond) // pass the clean interval
+
+	peerManager.updatePeers(context.Background(), []*Node{createTestNode("1.2.3.4")}, []string{"2.3.4.5"})
+
+	assert.Equal(t, []string{"1.2.3.4", "2.3.4.5"}, peerManager.GetKnownPeers())
+}
+
+// Runs refreshes, peer updates and readers concurrently. Meant to be run with -race.
+func TestPeerManager_concurrentRefreshAndDiscovery(t *testing.T) {
+	var polls atomic.Uint64
+	createNode := func(_ context.Context, host string) (*Node, error) {
+		var id int
+		_, err := fmt.Sscanf(host, "10.0.0.%d", &id)
+		if err != nil {
+			return nil, err
+		}
+		if id > 2 && polls.Add(1)%5 == 0 {
+			return nil, errors.New("offline")
+		}
+		node := createTestNodeWithPeers(host, []string{fmt.Sprintf("10.0.0.%d", (id+1)%40), fmt.Sprintf("10.0.0.%d", (id*7)%40)})
+		node.LastTick = 1000 - uint32(id) // different ticks, so that the refresh reorders the nodes
+		return node, nil
+	}
+	configured := []string{"10.0.0.1", "10.0.0.2"}
+	discovery := newPublicPeerDiscovery(createNode, []string{}, 0, NewWorkerPool(20), time.Second)
+	peerManager := newPeerManagerWithCreateNodeFunction(configured, discovery, createNode, NewWorkerPool(20), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for ctx.Err() == nil {
+				peerManager.GetKnownPeers()
+				peerManager.GetNumberOfKnownNodes()
+				peerManager.ExportPeers()
+				container.GetNodes()
+				container.GetResponse()
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+	}
+	var refreshes sync.WaitGroup
+	refreshes.Add(2)
+	go func() {
+		defer refreshes.Done()
+		for range 20 {
+			assert.NoError(t, container.Update(ctx))
+		}
+	}()
+	go func() {
+		defer refreshes.Done()
+		for range 20 {
+			peerManager.UpdateNodes(ctx)
+		}
+	}()
+	refreshes.Wait()
+	cancel()
+	readers.Wait()
+	peerManager.peerUpdateLock.Lock() // wait for the last peer update
+	defer peerManager.peerUpdateLock.Unlock()
+
+	peers := peerManager.GetKnownPeers()
+	assert.Subset(t, peers, configured)
+	slices.Sort(peers)
+	assert.Len(t, slices.Compact(peers), len(peers), "peers must be unique")
+}
+
+func TestPeerManager_UpdateNodes_whenCallerReordersNodes_thenKeepNodesOfPeerUpdate(t *testing.T) {
+	addresses := []string{"1.2.3.4", "2.3.4.5", "3.4.5.6", "4.5.6.7"}
+	discovery := &blockingPeerDiscovery{started: make(chan []string, 1), release: make(chan struct{})}
+	peerManager := newPeerManagerWithCreateNodeFunction(addresses, discovery, createTestNodes, NewWorkerPool(10), time.Second)
+
+	nodes := peerManager.UpdateNodes(context.Background())
+	before := <-discovery.started
+	slices.Reverse(nodes)
+	close(discovery.release)
+	peerManager.peerUpdateLock.Lock() // wait for the peer update
+	defer peerManager.peerUpdateLock.Unlock()
+
+	assert.Len(t, nodes, 4)
+	assert.Equal(t, before, discovery.after)
+}
+
 func createTestNode(host string) *Node {
 	return createTestNodeWithPeers(host, []string{})
 }
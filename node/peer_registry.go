@@ -0,0 +1,58 @@
+package node
+
+import (
+	"slices"
+	"sync"
+)
+
+// peerRegistry holds a set of peer addresses. It is safe for concurrent use.
+type peerRegistry struct {
+	peers []string
+	lock  sync.RWMutex
+}
+
+func newPeerRegistry(peers []string) *peerRegistry {
+	return &peerRegistry{peers: slices.Clone(peers)}
+}
+
+// list returns a copy of the known peers.
+func (pr *peerRegistry) list() []string {
+	pr.lock.RLock()
+	defer pr.lock.RUnlock()
+	return slices.Clone(pr.peers)
+}
+
+func (pr *peerRegistry) size() int {
+	pr.lock.RLock()
+	defer pr.lock.RUnlock()
+	return len(pr.peers)
+}
+
+// set replaces the peers.
+func (pr *peerRegistry) set(peers []string) {
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	pr.peers = slices.Clone(peers)
+}
+
+// add adds the peer and returns true, if it was not known before.
+func (pr *peerRegistry) add(host string) bool {
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	if slices.Contains(pr.peers, host) {
+		return false
+	}
+	pr.peers = append(pr.peers, host)
+	return true
+}
+
+// remove removes the peer and returns true, if it was known before.
+func (pr *peerRegistry) remove(host string) bool {
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	length := len(pr.peers)
+	pr.peers = slices.DeleteFunc(pr.peers, func(peer string) bool {
+		return peer == host
+	})
+	return len(pr.peers) < length
+}
@@ -0,0 +1,90 @@
+package node
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type StoredPeers struct {
+	SavedAt time.Time    `json:"saved_at"`
+	Peers   []StoredPeer `json:"peers"`
+}
+
+type StoredPeer struct {
+	Address       string               `json:"address"`
+	LastSeen      time.Time            `json:"last_seen,omitempty"`
+	LastFailure   time.Time            `json:"last_failure,omitempty"`
+	LastError     string               `json:"last_error,omitempty"`
+	LastErrorType string               `json:"last_error_type,omitempty"`
+	Availability  []StoredAvailability `json:"availability,omitempty"`
+	Sync          []StoredSync         `json:"sync,omitempty"`
+}
+
+type StoredAvailability struct {
+	At      time.Time     `json:"at,omitempty"`
+	Success bool          `json:"success"`
+	Latency time.Duration `json:"latency"`
+}
+
+type StoredSync struct {
+	Reliable bool   `json:"reliable"`
+	Lag      uint32 `json:"lag"`
+}
+
+// PeerStore persists the known peers and their health history in a JSON file.
+type PeerStore struct {
+	path string
+}
+
+func NewPeerStore(path string) *PeerStore {
+	return &PeerStore{path: path}
+}
+
+// Load returns the stored peers. A missing file is not an error.
+func (ps *PeerStore) Load() (StoredPeers, error) {
+	data, err := os.ReadFile(ps.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return StoredPeers{}, nil
+	}
+	if err != nil {
+		return StoredPeers{}, errors.Wrap(err, "reading peer store")
+	}
+
+	var stored StoredPeers
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		return StoredPeers{}, errors.Wrap(err, "decoding peer store")
+	}
+	return stored, nil
+}
+
+// Save writes the peers to a temporary file first, so that the store is not corrupted if writing fails.
+func (ps *PeerStore) Save(stored StoredPeers) error {
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return errors.Wrap(err, "encoding peer store")
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(ps.path), filepath.Base(ps.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating temporary peer store")
+	}
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "writing temporary peer store")
+	}
+
+	err = os.Rename(temp.Name(), ps.path)
+	if err != nil {
+		return errors.Wrap(err, "replacing peer store")
+	}
+	return nil
+}
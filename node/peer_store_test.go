@@ -0,0 +1,72 @@
+package node
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPeerStore_SaveAndLoad(t *testing.T) {
+	store := NewPeerStore(filepath.Join(t.TempDir(), "peers.json"))
+
+	stored := StoredPeers{
+		SavedAt: time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC),
+		Peers: []StoredPeer{
+			{
+				Address:      "1.2.3.4",
+				LastSeen:     time.Date(2024, 5, 2, 11, 0, 0, 0, time.UTC),
+				Availability: []StoredAvailability{{Success: true, Latency: 10 * time.Millisecond}},
+				Sync:         []StoredSync{{Reliable: true, Lag: 2}},
+			},
+			{Address: "2.3.4.5"},
+		},
+	}
+	require.NoError(t, store.Save(stored))
+
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, stored, loaded)
+}
+
+func TestPeerStore_Load_missingFile(t *testing.T) {
+	store := NewPeerStore(filepath.Join(t.TempDir(), "peers.json"))
+
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, loaded.Peers)
+}
+
+func TestPeerStore_Load_corruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+
+	_, err := NewPeerStore(path).Load()
+	assert.Error(t, err)
+}
+
+func TestPeerManager_ExportAndRestorePeers(t *testing.T) {
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "6.6.6.6"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	for i := 0; i < healthHistorySize+5; i++ {
+		peerManager.health.RecordPoll("1.2.3.4", i%2 == 0, time.Duration(i)*time.Millisecond)
+	}
+	peerManager.UpdateNodes(context.Background())
+	exported := peerManager.ExportPeers()
+	require.Len(t, exported, 2)
+	assert.Len(t, exported[0].Availability, healthHistorySize)
+	// oldest first
+	assert.Equal(t, 6*time.Millisecond, exported[0].Availability[0].Latency)
+
+	restored := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	restored.RestorePeers(exported)
+
+	assert.Equal(t, 2, restored.GetNumberOfKnownNodes())
+	assert.Equal(t, 1, restored.GetNumberOfConfiguredNodes())
+	originalStats, _ := peerManager.GetNodeHealth("1.2.3.4")
+	restoredStats, ok := restored.GetNodeHealth("1.2.3.4")
+	require.True(t, ok)
+	assert.Equal(t, originalStats, restoredStats)
+}
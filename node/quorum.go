@@ -0,0 +1,60 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Quorum is the minimum number of online nodes that need to agree on the max tick. It is either an absolute number
+// of nodes (for example "3") or a percentage of the online nodes (for example "50%").
+type Quorum struct {
+	count   uint32
+	percent float64
+}
+
+func NewQuorumCount(count uint32) Quorum {
+	return Quorum{count: count}
+}
+
+func NewQuorumPercent(percent float64) Quorum {
+	return Quorum{percent: percent}
+}
+
+func ParseQuorum(value string) (Quorum, error) {
+	value = strings.TrimSpace(value)
+	if percentValue, found := strings.CutSuffix(value, "%"); found {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(percentValue), 64)
+		if err != nil {
+			return Quorum{}, errors.Wrapf(err, "parsing quorum percentage [%s]", value)
+		}
+		if percent < 0 || percent > 100 {
+			return Quorum{}, errors.Errorf("quorum percentage [%s] out of range", value)
+		}
+		return NewQuorumPercent(percent), nil
+	}
+
+	count, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return Quorum{}, errors.Wrapf(err, "parsing quorum count [%s]", value)
+	}
+	return NewQuorumCount(uint32(count)), nil
+}
+
+func (q Quorum) String() string {
+	if q.percent > 0 {
+		return strconv.FormatFloat(q.percent, 'f', -1, 64) + "%"
+	}
+	return strconv.FormatUint(uint64(q.count), 10)
+}
+
+// RequiredNodes returns the number of agreeing nodes needed for the given number of online nodes. At least one node
+// is always required.
+func (q Quorum) RequiredNodes(onlineNodes int) int {
+	required := int(q.count)
+	if q.percent > 0 {
+		required = int(math.Ceil(float64(onlineNodes) * q.percent / 100))
+	}
+	return max(required, 1)
+}
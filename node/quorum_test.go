@@ -0,0 +1,44 @@
+package node
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseQuorum(t *testing.T) {
+	testData := []struct {
+		name  string
+		value string
+		want  Quorum
+	}{
+		{name: "TestParseQuorum_count", value: "3", want: NewQuorumCount(3)},
+		{name: "TestParseQuorum_count_with_spaces", value: " 3 ", want: NewQuorumCount(3)},
+		{name: "TestParseQuorum_percent", value: "50%", want: NewQuorumPercent(50)},
+		{name: "TestParseQuorum_fractional_percent", value: "66.6%", want: NewQuorumPercent(66.6)},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseQuorum(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestParseQuorum_invalid(t *testing.T) {
+	for _, value := range []string{"", "abc", "-1", "101%", "x%"} {
+		_, err := ParseQuorum(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestQuorum_RequiredNodes(t *testing.T) {
+	assert.Equal(t, 1, NewQuorumCount(0).RequiredNodes(10))
+	assert.Equal(t, 3, NewQuorumCount(3).RequiredNodes(10))
+	assert.Equal(t, 3, NewQuorumCount(3).RequiredNodes(1))
+	assert.Equal(t, 5, NewQuorumPercent(50).RequiredNodes(10))
+	assert.Equal(t, 4, NewQuorumPercent(50).RequiredNodes(7))
+	assert.Equal(t, 1, NewQuorumPercent(50).RequiredNodes(0))
+}
@@ -0,0 +1,180 @@
+package node
+
+import (
+	"cmp"
+	"github.com/pkg/errors"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	SelectionScore          = "score"
+	SelectionLatency        = "latency"
+	SelectionUptime         = "uptime"
+	SelectionRoundRobin     = "round-robin"
+	SelectionWeightedRandom = "weighted-random"
+)
+
+// SelectionPolicy picks the most reliable node out of the reliable nodes.
+type SelectionPolicy interface {
+	Select(nodes []*Node, stats map[string]HealthStats) *Node
+}
+
+// perRequestSelection is implemented by policies that spread the load across clients. They select a node for every
+// request instead of once per refresh.
+type perRequestSelection interface {
+	SelectionPolicy
+	selectsPerRequest()
+}
+
+func NewSelectionPolicy(name string, topNodes int) (SelectionPolicy, error) {
+	switch name {
+	case SelectionScore:
+		return &ScoreSelection{}, nil
+	case SelectionLatency:
+		return &LatencySelection{}, nil
+	case SelectionUptime:
+		return &UptimeSelection{}, nil
+	case SelectionRoundRobin:
+		return NewRoundRobinSelection(topNodes), nil
+	case SelectionWeightedRandom:
+		return NewWeightedRandomSelection(topNodes, rand.NewSource(time.Now().UnixNano())), nil
+	default:
+		return nil, errors.Errorf("unknown selection policy [%s]", name)
+	}
+}
+
+// ScoreSelection picks the node with the highest health score.
+type ScoreSelection struct{}
+
+func (s *ScoreSelection) Select(nodes []*Node, stats map[string]HealthStats) *Node {
+	return first(rankNodes(nodes, stats, compareScore))
+}
+
+// LatencySelection picks the node with the lowest median latency.
+type LatencySelection struct{}
+
+func (s *LatencySelection) Select(nodes []*Node, stats map[string]HealthStats) *Node {
+	return first(rankNodes(nodes, stats, func(a, b HealthStats) int {
+		return cmp.Compare(b.LatencyP50, a.LatencyP50)
+	}))
+}
+
+// UptimeSelection picks the node with the highest historical success ratio.
+type UptimeSelection struct{}
+
+func (s *UptimeSelection) Select(nodes []*Node, stats map[string]HealthStats) *Node {
+	return first(rankNodes(nodes, stats, func(a, b HealthStats) int {
+		return cmp.Compare(a.SuccessRatio, b.SuccessRatio)
+	}))
+}
+
+// RoundRobinSelection rotates through the top nodes by score with every selection. It selects per request.
+type RoundRobinSelection struct {
+	topNodes int
+	next     int
+	lock     sync.Mutex
+}
+
+func NewRoundRobinSelection(topNodes int) *RoundRobinSelection {
+	return &RoundRobinSelection{topNodes: max(topNodes, 1)}
+}
+
+func (s *RoundRobinSelection) selectsPerRequest() {}
+
+func (s *RoundRobinSelection) Select(nodes []*Node, stats map[string]HealthStats) *Node {
+	ranked := rankNodes(nodes, stats, compareScore)
+	if len(ranked) == 0 {
+		return nil
+	}
+	top := ranked[:min(s.topNodes, len(ranked))]
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	selected := top[s.next%len(top)]
+	s.next++
+	return selected
+}
+
+// WeightedRandomSelection picks a random node out of the top nodes by score, weighted by score. It selects per
+// request.
+type WeightedRandomSelection struct {
+	topNodes int
+	random   *rand.Rand
+	lock     sync.Mutex
+}
+
+func NewWeightedRandomSelection(topNodes int, source rand.Source) *WeightedRandomSelection {
+	return &WeightedRandomSelection{
+		topNodes: max(topNodes, 1),
+		random:   rand.New(source),
+	}
+}
+
+func (s *WeightedRandomSelection) selectsPerRequest() {}
+
+func (s *WeightedRandomSelection) Select(nodes []*Node, stats map[string]HealthStats) *Node {
+	ranked := rankNodes(nodes, stats, compareScore)
+	if len(ranked) == 0 {
+		return nil
+	}
+	top := ranked[:min(s.topNodes, len(ranked))]
+
+	var total float64
+	for _, node := range top {
+		total += stats[node.Address].Score
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if total == 0 {
+		return top[s.random.Intn(len(top))]
+	}
+	threshold := s.random.Float64() * total
+	for _, node := range top {
+		threshold -= stats[node.Address].Score
+		if threshold < 0 {
+			return node
+		}
+	}
+	return top[len(top)-1]
+}
+
+func compareScore(a, b HealthStats) int {
+	return cmp.Compare(a.Score, b.Score)
+}
+
+// rankNodes sorts the nodes from best to worst. Nodes without statistics rank last. Ties are broken by the higher tick
+// and then by the lower address, so that the ranking does not depend on the order of the nodes.
+func rankNodes(nodes []*Node, stats map[string]HealthStats, compare func(a, b HealthStats) int) []*Node {
+	ranked := slices.Clone(nodes)
+	slices.SortStableFunc(ranked, func(a, b *Node) int {
+		aStats, aKnown := stats[a.Address]
+		bStats, bKnown := stats[b.Address]
+		if aKnown != bKnown {
+			if aKnown {
+				return -1
+			}
+			return 1
+		}
+		if aKnown {
+			if c := compare(aStats, bStats); c != 0 {
+				return -c
+			}
+		}
+		if c := cmp.Compare(a.LastTick, b.LastTick); c != 0 {
+			return -c
+		}
+		return cmp.Compare(a.Address, b.Address)
+	})
+	return ranked
+}
+
+func first(nodes []*Node) *Node {
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
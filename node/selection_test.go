@@ -0,0 +1,128 @@
+package node
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var (
+	selectionNodeA = &Node{Address: "1.2.3.4", LastTick: 1000}
+	selectionNodeB = &Node{Address: "2.3.4.5", LastTick: 1000}
+	selectionNodeC = &Node{Address: "3.4.5.6", LastTick: 999}
+)
+
+func TestNewSelectionPolicy(t *testing.T) {
+	for _, name := range []string{SelectionScore, SelectionLatency, SelectionUptime, SelectionRoundRobin, SelectionWeightedRandom} {
+		policy, err := NewSelectionPolicy(name, 3)
+		require.NoError(t, err, name)
+		assert.NotNil(t, policy, name)
+	}
+
+	_, err := NewSelectionPolicy("unknown", 3)
+	assert.Error(t, err)
+}
+
+func TestScoreSelection_Select(t *testing.T) {
+	policy := &ScoreSelection{}
+	assert.Nil(t, policy.Select([]*Node{}, map[string]HealthStats{}))
+
+	// highest score wins
+	stats := map[string]HealthStats{
+		selectionNodeA.Address: {Score: 0.5},
+		selectionNodeB.Address: {Score: 0.6},
+		selectionNodeC.Address: {Score: 0.9},
+	}
+	assert.Equal(t, selectionNodeC, policy.Select([]*Node{selectionNodeA, selectionNodeB, selectionNodeC}, stats))
+
+	// same score, higher tick wins, then lower address, independent of order
+	stats = map[string]HealthStats{
+		selectionNodeA.Address: {Score: 0.5},
+		selectionNodeB.Address: {Score: 0.5},
+		selectionNodeC.Address: {Score: 0.5},
+	}
+	assert.Equal(t, selectionNodeA, policy.Select([]*Node{selectionNodeA, selectionNodeB, selectionNodeC}, stats))
+	assert.Equal(t, selectionNodeA, policy.Select([]*Node{selectionNodeC, selectionNodeB, selectionNodeA}, stats))
+
+	// nodes without history rank last
+	stats = map[string]HealthStats{selectionNodeC.Address: {Score: 0}}
+	assert.Equal(t, selectionNodeC, policy.Select([]*Node{selectionNodeA, selectionNodeB, selectionNodeC}, stats))
+}
+
+func TestLatencySelection_Select(t *testing.T) {
+	stats := map[string]HealthStats{
+		selectionNodeA.Address: {LatencyP50: 30 * time.Millisecond},
+		selectionNodeB.Address: {LatencyP50: 10 * time.Millisecond},
+		selectionNodeC.Address: {LatencyP50: 20 * time.Millisecond},
+	}
+	policy := &LatencySelection{}
+	assert.Equal(t, selectionNodeB, policy.Select([]*Node{selectionNodeA, selectionNodeB, selectionNodeC}, stats))
+}
+
+func TestUptimeSelection_Select(t *testing.T) {
+	stats := map[string]HealthStats{
+		selectionNodeA.Address: {SuccessRatio: 0.5},
+		selectionNodeB.Address: {SuccessRatio: 0.9},
+		selectionNodeC.Address: {SuccessRatio: 1},
+	}
+	policy := &UptimeSelection{}
+	assert.Equal(t, selectionNodeC, policy.Select([]*Node{selectionNodeA, selectionNodeB, selectionNodeC}, stats))
+}
+
+func TestRoundRobinSelection_Select(t *testing.T) {
+	stats := map[string]HealthStats{
+		selectionNodeA.Address: {Score: 0.9},
+		selectionNodeB.Address: {Score: 0.8},
+		selectionNodeC.Address: {Score: 0.1},
+	}
+	nodes := []*Node{selectionNodeC, selectionNodeB, selectionNodeA}
+	policy := NewRoundRobinSelection(2)
+
+	assert.Equal(t, selectionNodeA, policy.Select(nodes, stats))
+	assert.Equal(t, selectionNodeB, policy.Select(nodes, stats))
+	assert.Equal(t, selectionNodeA, policy.Select(nodes, stats))
+	assert.Nil(t, policy.Select([]*Node{}, stats))
+}
+
+func TestWeightedRandomSelection_Select(t *testing.T) {
+	stats := map[string]HealthStats{
+		selectionNodeA.Address: {Score: 0.9},
+		selectionNodeB.Address: {Score: 0.1},
+		selectionNodeC.Address: {Score: 0.5},
+	}
+	nodes := []*Node{selectionNodeA, selectionNodeB, selectionNodeC}
+	policy := NewWeightedRandomSelection(2, rand.NewSource(42))
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[policy.Select(nodes, stats).Address]++
+	}
+	assert.NotContains(t, counts, selectionNodeB.Address) // not in top nodes
+	assert.True(t, counts[selectionNodeA.Address] > counts[selectionNodeC.Address])
+	assert.True(t, counts[selectionNodeC.Address] > 0)
+}
+
+func TestContainer_SelectMostReliableNode(t *testing.T) {
+	ticks := map[string]uint32{"1.2.3.4": 1000, "2.3.4.5": 1000, "3.4.5.6": 999}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}, &NoPeerDiscovery{}, createTestNodesWithTicks(ticks), NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), NewRoundRobinSelection(2), 0)
+
+	require.NoError(t, container.Update(context.Background()))
+	response := container.GetResponse()
+	// the refresh keeps the node with the best score, the score depends on the measured latencies
+	best := (&ScoreSelection{}).Select(response.ReliableNodes, peerManager.health.StatsOf(response.ReliableNodes))
+	assert.Equal(t, best.Address, response.MostReliableNode.Address)
+
+	var selected []string
+	for range 3 {
+		selected = append(selected, container.SelectMostReliableNode(response).Address)
+	}
+	assert.ElementsMatch(t, []string{"1.2.3.4", "2.3.4.5"}, selected[:2])
+	assert.Equal(t, selected[0], selected[2])
+
+	container.SelectionPolicy = &ScoreSelection{}
+	assert.Equal(t, response.MostReliableNode, container.SelectMostReliableNode(response))
+}
@@ -0,0 +1,113 @@
+package node
+
+import (
+	"slices"
+	"time"
+)
+
+// number of snapshots kept for reading by sequence number
+const snapshotHistorySize = 100
+
+// Snapshot is the container state after a refresh. Every refresh creates a new snapshot with the next sequence
+// number. The nodes are copies owned by the snapshot and must not be modified.
+type Snapshot struct {
+	Sequence         uint64
+	CreatedAt        time.Time
+	MaxTick          uint32
+	LastUpdate       int64
+	OnlineNodes      []*Node
+	ReliableNodes    []*Node
+	MostReliableNode *Node
+	OutlierNodes     []*TickOutlier
+	Degraded         bool
+	Stall            StallStatus
+	Epoch            EpochInfo
+	TickRate         TickRate
+}
+
+var emptySnapshot = &Snapshot{}
+
+// newSnapshot copies the nodes, so that later changes to the given nodes are not visible in the snapshot. A node that
+// is in several of the given lists is copied once.
+func newSnapshot(sequence uint64, onlineNodes []*Node, maxTick uint32, lastUpdate int64, reliableNodes []*Node,
+	mostReliableNode *Node, outlierNodes []*TickOutlier, degraded bool, stall StallStatus, epoch EpochInfo, tickRate TickRate) *Snapshot {
+	copies := make(map[*Node]*Node)
+	copyNode := func(node *Node) *Node {
+		if node == nil {
+			return nil
+		}
+		if copied, ok := copies[node]; ok {
+			return copied
+		}
+		copied := *node
+		copied.Peers = slices.Clone(node.Peers)
+		copies[node] = &copied
+		return &copied
+	}
+	copyAll := func(nodes []*Node) []*Node {
+		if nodes == nil {
+			return nil
+		}
+		result := make([]*Node, 0, len(nodes))
+		for _, node := range nodes {
+			result = append(result, copyNode(node))
+		}
+		return result
+	}
+
+	snapshot := &Snapshot{
+		Sequence:         sequence,
+		CreatedAt:        time.Now(),
+		MaxTick:          maxTick,
+		LastUpdate:       lastUpdate,
+		OnlineNodes:      copyAll(onlineNodes),
+		ReliableNodes:    copyAll(reliableNodes),
+		MostReliableNode: copyNode(mostReliableNode),
+		Degraded:         degraded,
+		Stall:            stall,
+		Epoch:            epoch,
+		TickRate:         tickRate,
+	}
+	for _, outlier := range outlierNodes {
+		snapshot.OutlierNodes = append(snapshot.OutlierNodes, &TickOutlier{Node: copyNode(outlier.Node), Reason: outlier.Reason})
+	}
+	return snapshot
+}
+
+// GetSnapshot returns the latest snapshot. Before the first refresh an empty snapshot with sequence number 0 is
+// returned.
+func (c *Container) GetSnapshot() *Snapshot {
+	snapshot := c.snapshot.Load()
+	if snapshot == nil {
+		return emptySnapshot
+	}
+	return snapshot
+}
+
+// GetSnapshotBySequence returns the snapshot with the given sequence number. Only the latest snapshots are kept, false
+// is returned for older and unknown sequence numbers.
+func (c *Container) GetSnapshotBySequence(sequence uint64) (*Snapshot, bool) {
+	c.mutexLock.RLock()
+	defer c.mutexLock.RUnlock()
+
+	if len(c.snapshots) == 0 {
+		return nil, false
+	}
+	first := c.snapshots[0].Sequence
+	if sequence < first || sequence >= first+uint64(len(c.snapshots)) {
+		return nil, false
+	}
+	return c.snapshots[sequence-first], true
+}
+
+// ReliableNodesWithMinimumTick returns the reliable nodes at or above the given tick.
+func (s *Snapshot) ReliableNodesWithMinimumTick(tick uint32) []*Node {
+	reliableNodesAtMinimumTick := make([]*Node, 0, len(s.ReliableNodes))
+	for _, node := range s.ReliableNodes {
+		if node.LastTick >= tick {
+			reliableNodesAtMinimumTick = append(reliableNodesAtMinimumTick, node)
+		}
+	}
+
+	return reliableNodesAtMinimumTick
+}
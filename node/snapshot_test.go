@@ -0,0 +1,78 @@
+package node
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestContainer_Set_thenSnapshotIsIsolated(t *testing.T) {
+	reliable := createTestNodeWithPeers("1.2.3.4", []string{"2.3.4.5"})
+	outlier := createTestNode("6.6.6.6")
+	container := &Container{}
+
+	container.Set([]*Node{reliable, outlier}, 42, 1500000000, []*Node{reliable}, reliable, []*TickOutlier{{Node: outlier, Reason: "ahead"}}, false, StallStatus{}, EpochInfo{}, TickRate{})
+	reliable.LastTick = 99
+	reliable.Peers[0] = "9.9.9.9"
+	outlier.LastTick = 99
+
+	snapshot := container.GetSnapshot()
+	assert.Equal(t, uint64(1), snapshot.Sequence)
+	assert.Equal(t, uint32(42), snapshot.ReliableNodes[0].LastTick)
+	assert.Equal(t, "2.3.4.5", snapshot.ReliableNodes[0].Peers[0])
+	assert.Equal(t, uint32(42), snapshot.OutlierNodes[0].Node.LastTick)
+	assert.True(t, snapshot.ReliableNodes[0] == snapshot.MostReliableNode)
+	assert.True(t, snapshot.OnlineNodes[0] == snapshot.MostReliableNode)
+}
+
+func TestContainer_GetSnapshotBySequence(t *testing.T) {
+	container := &Container{}
+	assert.Equal(t, uint64(0), container.GetSnapshot().Sequence)
+	_, ok := container.GetSnapshotBySequence(0)
+	assert.False(t, ok)
+
+	for tick := uint32(1); tick <= snapshotHistorySize+1; tick++ {
+		container.Set(nil, tick, 0, nil, nil, nil, false, StallStatus{}, EpochInfo{}, TickRate{})
+	}
+
+	latest := container.GetSnapshot()
+	assert.Equal(t, uint64(snapshotHistorySize+1), latest.Sequence)
+	snapshot, ok := container.GetSnapshotBySequence(2)
+	require.True(t, ok)
+	assert.Equal(t, uint32(2), snapshot.MaxTick)
+	snapshot, ok = container.GetSnapshotBySequence(latest.Sequence)
+	require.True(t, ok)
+	assert.True(t, latest == snapshot)
+
+	_, ok = container.GetSnapshotBySequence(1)
+	assert.False(t, ok, "oldest snapshot must be dropped")
+	_, ok = container.GetSnapshotBySequence(latest.Sequence + 1)
+	assert.False(t, ok)
+}
+
+func TestCalculateMaxTick_keepsOrderOfNodes(t *testing.T) {
+	nodes := []*Node{{Address: "1.2.3.4", LastTick: 30}, {Address: "2.3.4.5", LastTick: 10}, {Address: "3.4.5.6", LastTick: 20}}
+
+	maxTick, _ := calculateMaxTick(nodes, 50)
+
+	assert.Equal(t, uint32(30), maxTick)
+	assert.Equal(t, []string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}, addressesOf(nodes))
+}
+
+func TestContainer_Set_thenStateBelongsToSnapshot(t *testing.T) {
+	container := &Container{}
+	stall := StallStatus{Stalled: true, Reason: StallReasonNetwork}
+	epoch := EpochInfo{Epoch: 100, InitialTick: 1000}
+	tickRate := TickRate{TicksPerSecond: 0.5, MaxTick: 1010, Samples: 2}
+
+	container.Set(nil, 1010, 0, nil, nil, nil, false, stall, epoch, tickRate)
+	first := container.GetSnapshot()
+	container.Set(nil, 1020, 0, nil, nil, nil, false, StallStatus{}, EpochInfo{Epoch: 101}, TickRate{})
+
+	assert.Equal(t, stall, first.Stall)
+	assert.Equal(t, epoch, first.Epoch)
+	assert.Equal(t, tickRate, first.TickRate)
+	assert.Equal(t, uint16(101), container.GetEpoch().Epoch)
+	assert.False(t, container.GetResponse().Stall.Stalled)
+	assert.False(t, container.GetTickRate().Known())
+}
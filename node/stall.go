@@ -0,0 +1,54 @@
+package node
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	// StallReasonNetwork means the nodes are reachable and agree on the max tick, but the network stopped producing
+	// ticks.
+	StallReasonNetwork = "network"
+	// StallReasonConnectivity means the max tick did not advance because not enough nodes could be reached.
+	StallReasonConnectivity = "connectivity"
+)
+
+// StallStatus tells if the max tick did not advance for longer than the stall timeout. Since is the time of the last
+// max tick advance.
+type StallStatus struct {
+	Stalled bool
+	Since   time.Time
+	Reason  string
+}
+
+// stallDetector tracks the max tick progression. It is only used by the refresh and is not safe for concurrent use.
+type stallDetector struct {
+	maxTick     uint32
+	lastAdvance time.Time
+	stalled     bool
+}
+
+// observe records the max tick of a refresh. A timeout of 0 disables stall detection. Without online nodes or without
+// max tick quorum a stall is attributed to local connectivity, otherwise to the network.
+func (d *stallDetector) observe(maxTick uint32, onlineNodes int, degraded bool, timeout time.Duration, now time.Time) StallStatus {
+	if d.lastAdvance.IsZero() || maxTick > d.maxTick {
+		d.maxTick = maxTick
+		d.lastAdvance = now
+	}
+	status := StallStatus{Since: d.lastAdvance}
+	if timeout > 0 && now.Sub(d.lastAdvance) > timeout {
+		status.Stalled = true
+		status.Reason = StallReasonNetwork
+		if onlineNodes == 0 || degraded {
+			status.Reason = StallReasonConnectivity
+		}
+	}
+
+	if status.Stalled && !d.stalled {
+		log.Printf("Max tick %d did not advance since %s (%s).\n", d.maxTick, d.lastAdvance.UTC().Format(time.RFC3339), status.Reason)
+	} else if !status.Stalled && d.stalled {
+		log.Printf("Max tick advanced to %d. Stall resolved.\n", d.maxTick)
+	}
+	d.stalled = status.Stalled
+	return status
+}
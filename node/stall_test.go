@@ -0,0 +1,64 @@
+package node
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStallDetector_observe(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	detector := stallDetector{}
+
+	status := detector.observe(100, 3, false, time.Minute, start)
+	assert.Equal(t, StallStatus{Since: start}, status)
+
+	status = detector.observe(100, 3, false, time.Minute, start.Add(30*time.Second))
+	assert.Equal(t, StallStatus{Since: start}, status)
+
+	status = detector.observe(100, 3, false, time.Minute, start.Add(2*time.Minute))
+	assert.Equal(t, StallStatus{Stalled: true, Since: start, Reason: StallReasonNetwork}, status)
+
+	status = detector.observe(100, 0, true, time.Minute, start.Add(3*time.Minute))
+	assert.Equal(t, StallStatus{Stalled: true, Since: start, Reason: StallReasonConnectivity}, status)
+
+	status = detector.observe(101, 3, false, time.Minute, start.Add(4*time.Minute))
+	assert.Equal(t, StallStatus{Since: start.Add(4 * time.Minute)}, status)
+}
+
+func TestStallDetector_observe_whenTickDecreases_thenNoAdvance(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	detector := stallDetector{}
+
+	detector.observe(100, 3, false, time.Minute, start)
+	status := detector.observe(99, 3, false, time.Minute, start.Add(2*time.Minute))
+
+	assert.Equal(t, StallStatus{Stalled: true, Since: start, Reason: StallReasonNetwork}, status)
+}
+
+func TestStallDetector_observe_whenTimeoutDisabled_thenNeverStalled(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	detector := stallDetector{}
+
+	detector.observe(100, 3, false, 0, start)
+	status := detector.observe(100, 3, false, 0, start.Add(time.Hour))
+
+	assert.False(t, status.Stalled)
+}
+
+func TestContainer_Update_whenMaxTickDoesNotAdvance_thenStalled(t *testing.T) {
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4", "2.3.4.5"}, &NoPeerDiscovery{}, createTestNodes, NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 10*time.Millisecond)
+
+	assert.NoError(t, container.Update(context.Background()))
+	assert.False(t, container.GetResponse().Stall.Stalled)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, container.Update(context.Background()))
+
+	stall := container.GetResponse().Stall
+	assert.True(t, stall.Stalled)
+	assert.Equal(t, StallReasonNetwork, stall.Reason)
+	assert.Len(t, container.GetResponse().ReliableNodes, 2)
+}
@@ -0,0 +1,64 @@
+package node
+
+import (
+	"math"
+	"time"
+)
+
+// TargetTick is a recommended tick for broadcasting transactions. It is the max tick extrapolated to now, plus the
+// tick spread of the reliable nodes and the safety margin. It is at least the minimum offset ahead of the max tick.
+type TargetTick struct {
+	Tick          uint32
+	MaxTick       uint32
+	EstimatedTick uint32
+	Spread        uint32
+	MarginTicks   uint32
+	TickRateKnown bool
+}
+
+// RecommendTargetTick returns a target tick based on the snapshot. The margin is converted into ticks with the
+// measured tick rate. Without a known tick rate only the spread and the minimum offset are added.
+func (s *Snapshot) RecommendTargetTick(margin time.Duration, minOffset uint32, now time.Time) TargetTick {
+	target := TargetTick{
+		MaxTick:       s.MaxTick,
+		EstimatedTick: s.MaxTick,
+		Spread:        tickSpread(s.ReliableNodes),
+		TickRateKnown: s.TickRate.Known(),
+	}
+	if target.TickRateKnown {
+		// the tick rate keeps the last known max tick, if a refresh found no online node
+		target.MaxTick = max(target.MaxTick, s.TickRate.MaxTick)
+		target.EstimatedTick = target.MaxTick
+		if elapsed := now.Sub(s.TickRate.At); elapsed > 0 {
+			target.EstimatedTick = max(target.MaxTick, addTicks(s.TickRate.MaxTick, uint64(ticksIn(elapsed, s.TickRate.TickDuration))))
+		}
+		target.MarginTicks = ticksIn(margin, s.TickRate.TickDuration)
+	}
+	target.Tick = max(addTicks(target.EstimatedTick, uint64(target.Spread)+uint64(target.MarginTicks)), addTicks(target.MaxTick, uint64(minOffset)))
+	return target
+}
+
+// ticksIn returns the number of ticks in the duration, rounded up.
+func ticksIn(duration, tickDuration time.Duration) uint32 {
+	if duration <= 0 || tickDuration <= 0 {
+		return 0
+	}
+	return uint32(min((duration+tickDuration-1)/tickDuration, math.MaxUint32))
+}
+
+func addTicks(tick uint32, ticks uint64) uint32 {
+	return uint32(min(uint64(tick)+ticks, math.MaxUint32))
+}
+
+// tickSpread returns the difference between the highest and the lowest tick of the nodes.
+func tickSpread(nodes []*Node) uint32 {
+	if len(nodes) == 0 {
+		return 0
+	}
+	lowest, highest := nodes[0].LastTick, nodes[0].LastTick
+	for _, node := range nodes[1:] {
+		lowest = min(lowest, node.LastTick)
+		highest = max(highest, node.LastTick)
+	}
+	return highest - lowest
+}
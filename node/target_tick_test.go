@@ -0,0 +1,56 @@
+package node
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_RecommendTargetTick(t *testing.T) {
+	at := time.Unix(1714654600, 0)
+	snapshot := &Snapshot{
+		MaxTick:       1000,
+		ReliableNodes: []*Node{{LastTick: 997}, {LastTick: 1000}, {LastTick: 999}},
+		TickRate:      TickRate{TicksPerSecond: 0.5, TickDuration: 2 * time.Second, MaxTick: 1000, At: at},
+	}
+
+	// 5s since the last refresh are 3 ticks rounded up, spread 3, 10s margin are 5 ticks
+	target := snapshot.RecommendTargetTick(10*time.Second, 5, at.Add(5*time.Second))
+
+	assert.Equal(t, TargetTick{
+		Tick:          1011,
+		MaxTick:       1000,
+		EstimatedTick: 1003,
+		Spread:        3,
+		MarginTicks:   5,
+		TickRateKnown: true,
+	}, target)
+}
+
+func TestSnapshot_RecommendTargetTick_atLeastMinimumOffset(t *testing.T) {
+	at := time.Unix(1714654600, 0)
+	snapshot := &Snapshot{
+		MaxTick:       1000,
+		ReliableNodes: []*Node{{LastTick: 1000}},
+		TickRate:      TickRate{TicksPerSecond: 0.5, TickDuration: 2 * time.Second, MaxTick: 1000, At: at},
+	}
+
+	target := snapshot.RecommendTargetTick(time.Second, 5, at)
+
+	assert.Equal(t, uint32(1005), target.Tick)
+	assert.Equal(t, uint32(1), target.MarginTicks)
+}
+
+func TestSnapshot_RecommendTargetTick_withoutTickRate(t *testing.T) {
+	snapshot := &Snapshot{
+		MaxTick:       1000,
+		ReliableNodes: []*Node{{LastTick: 990}, {LastTick: 1000}},
+	}
+
+	target := snapshot.RecommendTargetTick(time.Minute, 5, time.Now())
+
+	assert.False(t, target.TickRateKnown)
+	assert.Equal(t, uint32(1000), target.EstimatedTick)
+	assert.Equal(t, uint32(0), target.MarginTicks)
+	assert.Equal(t, uint32(1010), target.Tick)
+}
@@ -0,0 +1,84 @@
+package node
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+type TickOutlier struct {
+	Node   *Node
+	Reason string
+}
+
+type tickCluster struct {
+	nodes []*Node
+}
+
+func (tc *tickCluster) maxTick() uint32 {
+	return tc.nodes[len(tc.nodes)-1].LastTick
+}
+
+// minClusterSupport is the number of nodes a cluster needs, so that its tick can become the max tick.
+const minClusterSupport = 2
+
+// calculateMaxTick groups the reported ticks into clusters, where neighbouring ticks are not more than threshold
+// apart, and returns the highest tick of the highest cluster with at least minClusterSupport nodes. Lagging nodes
+// cannot outvote up-to-date ones this way. If no cluster has enough support, the lowest one wins, so that a single
+// runaway node never sets the max tick. Nodes reporting ticks above the consensus cluster are rejected as outliers.
+// A threshold of 0 disables outlier detection.
+func calculateMaxTick(nodes []*Node, threshold uint32) (uint32, []*TickOutlier) {
+	nodes = slices.Clone(nodes) // keep the order of the given nodes
+	slices.SortFunc(nodes, func(a, b *Node) int {
+		return cmp.Compare(a.LastTick, b.LastTick)
+	})
+
+	arrayLength := len(nodes)
+
+	if arrayLength == 0 {
+		return 0, nil
+	}
+
+	if threshold == 0 {
+		return nodes[arrayLength-1].LastTick, nil
+	}
+
+	clusters := clusterByTick(nodes, threshold)
+
+	consensus := clusters[0]
+	for i := len(clusters) - 1; i >= 0; i-- {
+		if len(clusters[i].nodes) >= minClusterSupport {
+			consensus = clusters[i]
+			break
+		}
+	}
+
+	maxTick := consensus.maxTick()
+
+	var outliers []*TickOutlier
+	for _, node := range nodes {
+		if node.LastTick > maxTick {
+			outliers = append(outliers, &TickOutlier{
+				Node: node,
+				Reason: fmt.Sprintf("tick %d is %d ticks ahead of consensus tick %d agreed by %d nodes (threshold %d)",
+					node.LastTick, node.LastTick-maxTick, maxTick, len(consensus.nodes), threshold),
+			})
+		}
+	}
+
+	return maxTick, outliers
+}
+
+// clusterByTick expects the nodes to be sorted by tick.
+func clusterByTick(nodes []*Node, threshold uint32) []*tickCluster {
+	var clusters []*tickCluster
+	current := &tickCluster{nodes: []*Node{nodes[0]}}
+	for _, node := range nodes[1:] {
+		if node.LastTick-current.maxTick() > threshold {
+			clusters = append(clusters, current)
+			current = &tickCluster{}
+		}
+		current.nodes = append(current.nodes, node)
+	}
+	return append(clusters, current)
+}
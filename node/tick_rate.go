@@ -0,0 +1,83 @@
+package node
+
+import (
+	"log"
+	"time"
+)
+
+// number of refreshes the tick rate is averaged over
+const tickRateWindowSize = 20
+
+// TickRate is the max tick progression averaged over the last refreshes. The tick duration is the average time between
+// two ticks in that window. Without progression the rate and the duration are 0.
+type TickRate struct {
+	TicksPerSecond float64
+	TickDuration   time.Duration
+	MaxTick        uint32
+	At             time.Time
+	Samples        int
+}
+
+// Known returns true, if the max tick advanced within the window.
+func (r TickRate) Known() bool {
+	return r.TicksPerSecond > 0
+}
+
+// EstimateTime returns when the network reaches the given tick, extrapolated from the last max tick. For a tick that
+// is already reached the time of the last max tick is returned. False is returned, if the tick rate is unknown.
+func (r TickRate) EstimateTime(tick uint32) (time.Time, bool) {
+	if tick <= r.MaxTick {
+		return r.At, !r.At.IsZero()
+	}
+	if !r.Known() {
+		return time.Time{}, false
+	}
+	return r.At.Add(time.Duration(tick-r.MaxTick) * r.TickDuration), true
+}
+
+type tickSample struct {
+	tick uint32
+	at   time.Time
+}
+
+// tickRateTracker keeps the max ticks of the last refreshes. It is only used by the refresh and is not safe for
+// concurrent use.
+type tickRateTracker struct {
+	samples []tickSample
+	epoch   uint16
+}
+
+// observe records the max tick of a refresh. An unknown max tick of 0 is ignored. If the max tick goes back or the
+// epoch changes the window is restarted, because the max tick jumps to the initial tick of the new epoch. An unknown
+// epoch of 0 is ignored.
+func (t *tickRateTracker) observe(maxTick uint32, epoch uint16, now time.Time) TickRate {
+	if epoch != 0 {
+		if t.epoch != 0 && epoch != t.epoch {
+			log.Printf("Epoch changed from %d to %d. Restarting tick rate measurement.\n", t.epoch, epoch)
+			t.samples = nil
+		}
+		t.epoch = epoch
+	}
+	if maxTick > 0 {
+		if len(t.samples) > 0 && maxTick < t.samples[len(t.samples)-1].tick {
+			log.Printf("Max tick went back from %d to %d. Restarting tick rate measurement.\n", t.samples[len(t.samples)-1].tick, maxTick)
+			t.samples = nil
+		}
+		t.samples = append(t.samples, tickSample{tick: maxTick, at: now})
+		if len(t.samples) > tickRateWindowSize {
+			t.samples = t.samples[1:]
+		}
+	}
+	if len(t.samples) == 0 {
+		return TickRate{}
+	}
+
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	rate := TickRate{MaxTick: last.tick, At: last.at, Samples: len(t.samples)}
+	elapsed := last.at.Sub(first.at)
+	if ticks := last.tick - first.tick; ticks > 0 && elapsed > 0 {
+		rate.TicksPerSecond = float64(ticks) / elapsed.Seconds()
+		rate.TickDuration = elapsed / time.Duration(ticks)
+	}
+	return rate
+}
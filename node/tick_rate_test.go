@@ -0,0 +1,110 @@
+package node
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTickRateTracker_observe(t *testing.T) {
+	var tracker tickRateTracker
+	start := time.Unix(1714654600, 0)
+
+	rate := tracker.observe(0, 100, start)
+	assert.Equal(t, TickRate{}, rate)
+
+	rate = tracker.observe(1000, 100, start)
+	assert.False(t, rate.Known())
+	assert.Equal(t, 1, rate.Samples)
+
+	tracker.observe(1010, 100, start.Add(15*time.Second))
+	rate = tracker.observe(1020, 100, start.Add(40*time.Second))
+	assert.Equal(t, 0.5, rate.TicksPerSecond)
+	assert.Equal(t, 2*time.Second, rate.TickDuration)
+	assert.Equal(t, uint32(1020), rate.MaxTick)
+	assert.Equal(t, start.Add(40*time.Second), rate.At)
+	assert.Equal(t, 3, rate.Samples)
+}
+
+func TestTickRateTracker_observe_keepsWindow(t *testing.T) {
+	var tracker tickRateTracker
+	start := time.Unix(1714654600, 0)
+	// slow at first, then one tick per second
+	tracker.observe(1000, 100, start)
+	var rate TickRate
+	for i := 1; i <= tickRateWindowSize; i++ {
+		rate = tracker.observe(uint32(2000+10*i), 100, start.Add(time.Hour+time.Duration(10*i)*time.Second))
+	}
+
+	assert.Equal(t, tickRateWindowSize, rate.Samples)
+	assert.Equal(t, 1.0, rate.TicksPerSecond)
+	assert.Equal(t, time.Second, rate.TickDuration)
+}
+
+func TestTickRateTracker_observe_whenTickGoesBack_thenRestart(t *testing.T) {
+	var tracker tickRateTracker
+	start := time.Unix(1714654600, 0)
+	tracker.observe(1000, 100, start)
+	tracker.observe(1010, 100, start.Add(10*time.Second))
+
+	rate := tracker.observe(500, 100, start.Add(20*time.Second))
+
+	assert.False(t, rate.Known())
+	assert.Equal(t, 1, rate.Samples)
+	assert.Equal(t, uint32(500), rate.MaxTick)
+}
+
+func TestTickRateTracker_observe_whenEpochChanges_thenRestart(t *testing.T) {
+	var tracker tickRateTracker
+	start := time.Unix(1714654600, 0)
+	tracker.observe(1000, 100, start)
+	tracker.observe(1010, 100, start.Add(10*time.Second))
+	tracker.observe(1020, 0, start.Add(20*time.Second)) // unknown epoch
+
+	rate := tracker.observe(5000000, 101, start.Add(30*time.Second))
+
+	assert.False(t, rate.Known())
+	assert.Equal(t, 1, rate.Samples)
+
+	rate = tracker.observe(5000010, 101, start.Add(40*time.Second))
+	assert.Equal(t, 1.0, rate.TicksPerSecond)
+}
+
+func TestTickRate_EstimateTime(t *testing.T) {
+	at := time.Unix(1714654600, 0)
+	rate := TickRate{TicksPerSecond: 0.5, TickDuration: 2 * time.Second, MaxTick: 1000, At: at}
+
+	eta, ok := rate.EstimateTime(1010)
+	assert.True(t, ok)
+	assert.Equal(t, at.Add(20*time.Second), eta)
+
+	eta, ok = rate.EstimateTime(990)
+	assert.True(t, ok)
+	assert.Equal(t, at, eta)
+
+	_, ok = TickRate{MaxTick: 1000, At: at}.EstimateTime(1010)
+	assert.False(t, ok)
+	_, ok = TickRate{}.EstimateTime(0)
+	assert.False(t, ok)
+}
+
+func TestContainer_Update_measuresTickRate(t *testing.T) {
+	ticks := map[string]uint32{"1.2.3.4": 1000}
+	peerManager := newPeerManagerWithCreateNodeFunction([]string{"1.2.3.4"}, &NoPeerDiscovery{}, createTestNodesWithTicks(ticks), NewWorkerPool(10), time.Second)
+	container := NewNodeContainer(peerManager, 50, 30, NewQuorumCount(1), nil, 0)
+
+	require.NoError(t, container.Update(context.Background()))
+	assert.False(t, container.GetTickRate().Known())
+
+	time.Sleep(20 * time.Millisecond)
+	ticks["1.2.3.4"] = 1010
+	require.NoError(t, container.Update(context.Background()))
+
+	rate := container.GetTickRate()
+	assert.True(t, rate.Known())
+	assert.Equal(t, uint32(1010), rate.MaxTick)
+	assert.Equal(t, 2, rate.Samples)
+	assert.Equal(t, rate, container.GetSnapshot().TickRate)
+}
@@ -0,0 +1,78 @@
+package node
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type WorkerPoolStats struct {
+	MaxInFlight int
+	InFlight    int64
+	Queued      int64
+	PeakQueued  int64
+	Completed   uint64
+	TimedOut    uint64
+}
+
+// WorkerPool limits the number of concurrent node dials. Tasks wait in a queue until a slot is free or their deadline
+// is exceeded.
+type WorkerPool struct {
+	slots      chan struct{}
+	inFlight   atomic.Int64
+	queued     atomic.Int64
+	peakQueued atomic.Int64
+	completed  atomic.Uint64
+	timedOut   atomic.Uint64
+}
+
+func NewWorkerPool(maxInFlight int) *WorkerPool {
+	return &WorkerPool{
+		slots: make(chan struct{}, max(maxInFlight, 1)),
+	}
+}
+
+// Run blocks until a slot is free and runs the task. If the context is done before, the task is not run and false is
+// returned.
+func (wp *WorkerPool) Run(ctx context.Context, task func()) bool {
+	queued := wp.queued.Add(1)
+	dialQueueGauge.Inc()
+	for peak := wp.peakQueued.Load(); queued > peak; peak = wp.peakQueued.Load() {
+		if wp.peakQueued.CompareAndSwap(peak, queued) {
+			break
+		}
+	}
+
+	select {
+	case wp.slots <- struct{}{}:
+		wp.queued.Add(-1)
+		dialQueueGauge.Dec()
+	case <-ctx.Done():
+		wp.queued.Add(-1)
+		dialQueueGauge.Dec()
+		wp.timedOut.Add(1)
+		dialTimeoutsCounter.Inc()
+		return false
+	}
+
+	wp.inFlight.Add(1)
+	dialsInFlightGauge.Inc()
+	defer func() {
+		wp.inFlight.Add(-1)
+		dialsInFlightGauge.Dec()
+		wp.completed.Add(1)
+		<-wp.slots
+	}()
+	task()
+	return true
+}
+
+func (wp *WorkerPool) Stats() WorkerPoolStats {
+	return WorkerPoolStats{
+		MaxInFlight: cap(wp.slots),
+		InFlight:    wp.inFlight.Load(),
+		Queued:      wp.queued.Load(),
+		PeakQueued:  wp.peakQueued.Load(),
+		Completed:   wp.completed.Load(),
+		TimedOut:    wp.timedOut.Load(),
+	}
+}
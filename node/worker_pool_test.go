@@ -0,0 +1,65 @@
+package node
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_Run_limitsConcurrency(t *testing.T) {
+	workerPool := NewWorkerPool(3)
+
+	var running, maxRunning int
+	var lock sync.Mutex
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			workerPool.Run(context.Background(), func() {
+				lock.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				lock.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				lock.Lock()
+				running--
+				lock.Unlock()
+			})
+		}()
+	}
+	waitGroup.Wait()
+
+	stats := workerPool.Stats()
+	assert.Equal(t, 3, maxRunning)
+	assert.Equal(t, 3, stats.MaxInFlight)
+	assert.Equal(t, uint64(20), stats.Completed)
+	assert.Equal(t, int64(0), stats.InFlight)
+	assert.Equal(t, int64(0), stats.Queued)
+	assert.True(t, stats.PeakQueued > 3, "peak queued: %d", stats.PeakQueued)
+}
+
+func TestWorkerPool_Run_timesOutWhenQueued(t *testing.T) {
+	workerPool := NewWorkerPool(1)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	go workerPool.Run(context.Background(), func() {
+		close(started)
+		<-block
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ran := workerPool.Run(ctx, func() {
+		t.Fatal("task must not run")
+	})
+	close(block)
+
+	assert.False(t, ran)
+	assert.Equal(t, uint64(1), workerPool.Stats().TimedOut)
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+type epochResponse struct {
+	Epoch        uint16            `json:"epoch"`
+	InitialTick  uint32            `json:"initial_tick"`
+	TickDuration uint16            `json:"tick_duration"`
+	Transitions  []epochTransition `json:"transitions"`
+}
+
+type epochTransition struct {
+	From        uint16 `json:"from"`
+	To          uint16 `json:"to"`
+	InitialTick uint32 `json:"initial_tick"`
+	MaxTick     uint32 `json:"max_tick"`
+	DetectedAt  int64  `json:"detected_at"`
+}
+
+// HandleEpoch returns the current network epoch with its initial tick and the epoch transitions detected since the
+// service started.
+func (h *PeersHandler) HandleEpoch(w http.ResponseWriter, _ *http.Request) {
+
+	if h.Container.IsInitializing() {
+		writeNotReady(w)
+		return
+	}
+
+	epoch := h.Container.GetEpoch()
+	response := epochResponse{
+		Epoch:        epoch.Epoch,
+		InitialTick:  epoch.InitialTick,
+		TickDuration: epoch.TickDuration,
+		Transitions:  make([]epochTransition, 0, len(epoch.Transitions)),
+	}
+	for _, transition := range epoch.Transitions {
+		response.Transitions = append(response.Transitions, epochTransition{
+			From:        transition.From,
+			To:          transition.To,
+			InitialTick: transition.InitialTick,
+			MaxTick:     transition.MaxTick,
+			DetectedAt:  transition.DetectedAt.Unix(),
+		})
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Printf("Failed to write response for epoch request. Err: %v\n", err)
+	}
+}
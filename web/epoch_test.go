@@ -0,0 +1,32 @@
+package web
+
+import (
+	"github.com/qubic/go-qubic-nodes/node"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_HandleEpoch(t *testing.T) {
+	container := &node.Container{}
+	handler := PeersHandler{Container: container}
+
+	rec := httptest.NewRecorder()
+	handler.HandleEpoch(rec, nil)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"epoch": 0, "initial_tick": 0, "tick_duration": 0, "transitions": []}`, rec.Body.String())
+}
+
+func TestHandler_HandleEpoch_whenInitializing_thenNotReady(t *testing.T) {
+	peerManager := node.NewPeerManager([]string{"1.2.3.4"}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
+	handler := PeersHandler{Container: node.NewNodeContainer(peerManager, 50, 30, node.NewQuorumCount(1), nil, 0)}
+
+	rec := httptest.NewRecorder()
+	handler.HandleEpoch(rec, nil)
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/qubic/go-qubic-nodes/node"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type etaResponse struct {
+	TargetTick       uint32  `json:"target_tick"`
+	MaxTick          uint32  `json:"max_tick"`
+	TicksRemaining   uint32  `json:"ticks_remaining"`
+	TicksPerSecond   float64 `json:"ticks_per_second"`
+	TickDurationMs   float64 `json:"tick_duration_ms"`
+	Reached          bool    `json:"reached"`
+	Eta              int64   `json:"eta"`
+	SecondsRemaining float64 `json:"seconds_remaining"`
+}
+
+// HandleEta estimates when the network reaches the tick given in the `tick` query parameter, based on the measured
+// tick rate.
+func (h *PeersHandler) HandleEta(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("tick")
+	tick, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte("invalid tick: " + value))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	if h.Container.IsInitializing() {
+		writeNotReady(w)
+		return
+	}
+
+	response, ok := newEtaResponse(h.Container.GetTickRate(), uint32(tick), time.Now())
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, err := w.Write([]byte("Tick rate is not known yet."))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Printf("Failed to write response for eta request. Err: %v\n", err)
+	}
+}
+
+// newEtaResponse returns false, if the tick is not reached yet and the tick rate is unknown.
+func newEtaResponse(rate node.TickRate, tick uint32, now time.Time) (etaResponse, bool) {
+	eta, ok := rate.EstimateTime(tick)
+	if !ok {
+		return etaResponse{}, false
+	}
+	response := etaResponse{
+		TargetTick:       tick,
+		MaxTick:          rate.MaxTick,
+		TicksPerSecond:   rate.TicksPerSecond,
+		TickDurationMs:   milliseconds(rate.TickDuration),
+		Reached:          tick <= rate.MaxTick,
+		Eta:              eta.Unix(),
+		SecondsRemaining: max(eta.Sub(now).Seconds(), 0),
+	}
+	if !response.Reached {
+		response.TicksRemaining = tick - rate.MaxTick
+	}
+	return response, true
+}
@@ -0,0 +1,58 @@
+package web
+
+import (
+	"github.com/qubic/go-qubic-nodes/node"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewEtaResponse(t *testing.T) {
+	at := time.Unix(1714654600, 0)
+	rate := node.TickRate{TicksPerSecond: 0.5, TickDuration: 2 * time.Second, MaxTick: 1000, At: at, Samples: 5}
+
+	response, ok := newEtaResponse(rate, 1010, at.Add(5*time.Second))
+	require.True(t, ok)
+	require.Equal(t, etaResponse{
+		TargetTick:       1010,
+		MaxTick:          1000,
+		TicksRemaining:   10,
+		TicksPerSecond:   0.5,
+		TickDurationMs:   2000,
+		Eta:              at.Unix() + 20,
+		SecondsRemaining: 15,
+	}, response)
+
+	response, ok = newEtaResponse(rate, 999, at.Add(5*time.Second))
+	require.True(t, ok)
+	require.True(t, response.Reached)
+	require.Equal(t, uint32(0), response.TicksRemaining)
+	require.Equal(t, 0.0, response.SecondsRemaining)
+
+	_, ok = newEtaResponse(node.TickRate{MaxTick: 1000, At: at}, 1010, at)
+	require.False(t, ok)
+}
+
+func TestHandler_HandleEta_errors(t *testing.T) {
+	handler := PeersHandler{Container: &node.Container{}}
+
+	for target, expected := range map[string]int{
+		"/eta":          http.StatusBadRequest,
+		"/eta?tick=abc": http.StatusBadRequest,
+		"/eta?tick=-1":  http.StatusBadRequest,
+		"/eta?tick=100": http.StatusServiceUnavailable,
+	} {
+		rec := httptest.NewRecorder()
+		handler.HandleEta(rec, httptest.NewRequest("GET", target, nil))
+		require.Equal(t, expected, rec.Code, target)
+	}
+
+	peerManager := node.NewPeerManager([]string{"1.2.3.4"}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
+	handler = PeersHandler{Container: node.NewNodeContainer(peerManager, 50, 30, node.NewQuorumCount(1), nil, 0)}
+	rec := httptest.NewRecorder()
+	handler.HandleEta(rec, httptest.NewRequest("GET", "/eta?tick=100", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"status": "initializing", "message": "Waiting for the first successful refresh."}`, rec.Body.String())
+}
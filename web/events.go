@@ -0,0 +1,135 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/qubic/go-qubic-nodes/node"
+	"log"
+	"net/http"
+	"time"
+)
+
+// comment sent to keep idle connections open through proxies
+const keepAliveInterval = 15 * time.Second
+
+type containerEvent struct {
+	MaxTick                 uint32   `json:"max_tick"`
+	PreviousMaxTick         uint32   `json:"previous_max_tick"`
+	LastUpdate              int64    `json:"last_update"`
+	MaxTickChanged          bool     `json:"max_tick_changed"`
+	ReliableNodesAdded      []string `json:"reliable_nodes_added"`
+	ReliableNodesRemoved    []string `json:"reliable_nodes_removed"`
+	MostReliableNode        string   `json:"most_reliable_node"`
+	MostReliableNodeChanged bool     `json:"most_reliable_node_changed"`
+	Epoch                   uint16   `json:"epoch"`
+	PreviousEpoch           uint16   `json:"previous_epoch"`
+	EpochChanged            bool     `json:"epoch_changed"`
+}
+
+func newContainerEvent(event node.ContainerEvent) containerEvent {
+	return containerEvent{
+		MaxTick:                 event.MaxTick,
+		PreviousMaxTick:         event.PreviousMaxTick,
+		LastUpdate:              event.LastUpdate,
+		MaxTickChanged:          event.MaxTickChanged,
+		ReliableNodesAdded:      emptyIfNil(event.ReliableNodesAdded),
+		ReliableNodesRemoved:    emptyIfNil(event.ReliableNodesRemoved),
+		MostReliableNode:        event.MostReliableNode,
+		MostReliableNodeChanged: event.MostReliableNodeChanged,
+		Epoch:                   event.Epoch,
+		PreviousEpoch:           event.PreviousEpoch,
+		EpochChanged:            event.EpochChanged,
+	}
+}
+
+// HandleEvents streams container changes as server-sent events. The first event is a snapshot of the current state,
+// where all reliable nodes are reported as added.
+func (h *PeersHandler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte("Streaming not supported."))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	events, unsubscribe := h.Container.Subscribe()
+	defer unsubscribe()
+
+	// the stream is long-lived, the server write timeout must not end it
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Add("Content-Type", "text/event-stream")
+	w.Header().Add("Cache-Control", "no-cache")
+	w.Header().Add("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	current := h.Container.GetSnapshot()
+	epoch := current.Epoch.Epoch
+	snapshot := node.ContainerEvent{
+		MaxTick:            current.MaxTick,
+		PreviousMaxTick:    current.MaxTick,
+		LastUpdate:         current.LastUpdate,
+		ReliableNodesAdded: addresses(current.ReliableNodes),
+		Epoch:              epoch,
+		PreviousEpoch:      epoch,
+	}
+	if current.MostReliableNode != nil {
+		snapshot.MostReliableNode = current.MostReliableNode.Address
+	}
+	if err := writeEvent(w, "snapshot", newContainerEvent(snapshot)); err != nil {
+		log.Printf("Failed to write event: %v\n", err)
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			if err := writeEvent(w, "update", newContainerEvent(event)); err != nil {
+				log.Printf("Failed to write event: %v\n", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, eventType string, event any) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
+	return err
+}
+
+func addresses(nodes []*node.Node) []string {
+	addresses := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		addresses = append(addresses, n.Address)
+	}
+	return addresses
+}
+
+func emptyIfNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
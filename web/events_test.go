@@ -0,0 +1,103 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/qubic/go-qubic-nodes/node"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_HandleEvents(t *testing.T) {
+	node1 := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	node2 := &node.Node{Address: "2.3.4.5", LastTick: 101}
+
+	container := &node.Container{}
+	container.Set([]*node.Node{node1}, 100, 1500000000, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	handler := PeersHandler{Container: container}
+	server := httptest.NewServer(http.HandlerFunc(handler.HandleEvents))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+
+	eventType, snapshot := readEvent(t, reader)
+	require.Equal(t, "snapshot", eventType)
+	require.Equal(t, uint32(100), snapshot.MaxTick)
+	require.Equal(t, []string{"1.2.3.4"}, snapshot.ReliableNodesAdded)
+	require.Equal(t, "1.2.3.4", snapshot.MostReliableNode)
+
+	container.Set([]*node.Node{node1, node2}, 101, 1500000001, []*node.Node{node2}, node2, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	eventType, update := readEvent(t, reader)
+	require.Equal(t, "update", eventType)
+	expected := containerEvent{
+		MaxTick:                 101,
+		PreviousMaxTick:         100,
+		LastUpdate:              1500000001,
+		MaxTickChanged:          true,
+		ReliableNodesAdded:      []string{"2.3.4.5"},
+		ReliableNodesRemoved:    []string{"1.2.3.4"},
+		MostReliableNode:        "2.3.4.5",
+		MostReliableNodeChanged: true,
+	}
+	require.Equal(t, expected, update)
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) (string, containerEvent) {
+	var eventType string
+	var event containerEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		case line == "" && eventType != "":
+			return eventType, event
+		}
+	}
+}
+
+func TestHandler_HandleEvents_ignoresServerWriteTimeout(t *testing.T) {
+	node1 := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	container := &node.Container{}
+	container.Set([]*node.Node{node1}, 100, 1500000000, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	handler := PeersHandler{Container: container}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler.HandleEvents))
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	eventType, _ := readEvent(t, reader)
+	require.Equal(t, "snapshot", eventType)
+
+	time.Sleep(150 * time.Millisecond)
+	container.Set([]*node.Node{node1}, 101, 1500000001, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	eventType, update := readEvent(t, reader)
+	require.Equal(t, "update", eventType)
+	require.Equal(t, uint32(101), update.MaxTick)
+}
@@ -2,31 +2,87 @@ package web
 
 import (
 	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
 	"github.com/qubic/go-node-connector/types"
 	"github.com/qubic/go-qubic-nodes/node"
 
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type PeersHandler struct {
-	Container *node.Container
+	Container           *node.Container
+	TargetTickMargin    time.Duration
+	TargetTickMinOffset uint32
 }
 
 type statusResponse struct {
+	Sequence                uint64         `json:"sequence"`
 	MaxTick                 uint32         `json:"max_tick"`
 	LastUpdate              int64          `json:"last_update"`
 	NumberOfConfiguredNodes int            `json:"number_of_configured_nodes"`
 	ReliableNodes           []reliableNode `json:"reliable_nodes"`
-	MostReliableNode        reliableNode   `json:"most_reliable_node"`
+	MostReliableNode        *reliableNode  `json:"most_reliable_node,omitempty"`
+	RejectedNodes           []rejectedNode `json:"rejected_nodes,omitempty"`
+	Degraded                bool           `json:"degraded"`
+	Stalled                 bool           `json:"stalled"`
+	StalledSince            int64          `json:"stalled_since,omitempty"`
+	StallReason             string         `json:"stall_reason,omitempty"`
+	Message                 string         `json:"message,omitempty"`
 }
 
 type reliableNode struct {
-	Address    string            `json:"address"`
-	Port       string            `json:"port"`
-	Peers      types.PublicPeers `json:"peers"`
-	LastTick   uint32            `json:"last_tick"`
-	LastUpdate int64             `json:"last_update"`
+	Address           string            `json:"address"`
+	Port              string            `json:"port"`
+	Peers             types.PublicPeers `json:"peers"`
+	LastTick          uint32            `json:"last_tick"`
+	LastUpdate        int64             `json:"last_update"`
+	ConnectLatencyMs  float64           `json:"connect_latency_ms"`
+	TickInfoLatencyMs float64           `json:"tick_info_latency_ms"`
+}
+
+func newReliableNode(n *node.Node) reliableNode {
+	return reliableNode{
+		Address:           n.Address,
+		Port:              n.Port,
+		Peers:             n.Peers,
+		LastTick:          n.LastTick,
+		LastUpdate:        n.LastUpdate,
+		ConnectLatencyMs:  milliseconds(n.ConnectLatency),
+		TickInfoLatencyMs: milliseconds(n.TickInfoLatency),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// sort orders supported for reliable nodes, by default nodes are returned in the order of the last refresh
+const sortByLatency = "latency"
+
+func sortReliableNodes(nodes []*node.Node, order string) ([]*node.Node, error) {
+	switch order {
+	case "":
+		return nodes, nil
+	case sortByLatency:
+		return node.SortByLatency(nodes), nil
+	default:
+		return nil, errors.Errorf("unknown sort order: %s", order)
+	}
+}
+
+type rejectedNode struct {
+	Address  string `json:"address"`
+	LastTick uint32 `json:"last_tick"`
+	Reason   string `json:"reason"`
+}
+
+type notReadyResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
 }
 
 type maxTickResponse struct {
@@ -34,51 +90,124 @@ type maxTickResponse struct {
 }
 
 type reliablePeersAtMinimumTickResponse struct {
-	RequestedMinimumTick uint32       `json:"requested_minimum_tick"`
-	ReliableNodes        []*node.Node `json:"reliable_nodes"`
+	Sequence             uint64            `json:"sequence"`
+	RequestedMinimumTick uint32            `json:"requested_minimum_tick"`
+	ReliableNodes        []minimumTickNode `json:"reliable_nodes"`
 }
 
-func (h *PeersHandler) HandleStatus(w http.ResponseWriter, _ *http.Request) {
+// minimumTickNode keeps the field names of the original response, which serialized the nodes directly, and adds the
+// latencies in milliseconds.
+type minimumTickNode struct {
+	Address           string
+	Port              string
+	Peers             types.PublicPeers
+	LastTick          uint32
+	LastUpdate        int64
+	LastUpdateSuccess bool
+	ConnectLatencyMs  float64 `json:"connect_latency_ms"`
+	TickInfoLatencyMs float64 `json:"tick_info_latency_ms"`
+}
 
-	containerResponse := h.Container.GetResponse()
+func newMinimumTickNode(n *node.Node) minimumTickNode {
+	return minimumTickNode{
+		Address:           n.Address,
+		Port:              n.Port,
+		Peers:             n.Peers,
+		LastTick:          n.LastTick,
+		LastUpdate:        n.LastUpdate,
+		LastUpdateSuccess: n.LastUpdateSuccess,
+		ConnectLatencyMs:  milliseconds(n.ConnectLatency),
+		TickInfoLatencyMs: milliseconds(n.TickInfoLatency),
+	}
+}
 
-	if len(containerResponse.ReliableNodes) == 0 {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_, err := w.Write([]byte("No online or reliable nodes found."))
+// getResponse returns the latest snapshot or the one with the given sequence number, if it is set. An error response
+// is written and false is returned, if the sequence number is invalid or the snapshot is not available.
+func (h *PeersHandler) getResponse(w http.ResponseWriter, sequence string) (node.ContainerResponse, bool) {
+	if sequence == "" {
+		return h.Container.GetResponse(), true
+	}
+	parsed, err := strconv.ParseUint(sequence, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte("invalid sequence: " + sequence))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return node.ContainerResponse{}, false
+	}
+	response, ok := h.Container.GetResponseBySequence(parsed)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, err := w.Write([]byte(fmt.Sprintf("Snapshot %d is not available.", parsed)))
 		if err != nil {
 			log.Printf("Failed to respond to request: %v\n", err)
 		}
+		return node.ContainerResponse{}, false
+	}
+	return response, true
+}
+
+func (h *PeersHandler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+
+	containerResponse, ok := h.getResponse(w, r.URL.Query().Get("sequence"))
+	if !ok {
 		return
 	}
 
-	var reliableNodes []reliableNode
-	for _, relNode := range containerResponse.ReliableNodes {
-		r := reliableNode{
-			Address:    relNode.Address,
-			Port:       relNode.Port,
-			Peers:      relNode.Peers,
-			LastTick:   relNode.LastTick,
-			LastUpdate: relNode.LastUpdate,
+	if containerResponse.Initializing {
+		writeNotReady(w)
+		return
+	}
+
+	sortedNodes, err := sortReliableNodes(containerResponse.ReliableNodes, r.URL.Query().Get("sort"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
 		}
-		reliableNodes = append(reliableNodes, r)
+		return
 	}
 
-	mostReliable := containerResponse.MostReliableNode
+	var reliableNodes []reliableNode
+	for _, relNode := range sortedNodes {
+		reliableNodes = append(reliableNodes, newReliableNode(relNode))
+	}
 
-	mostReliableResponse := reliableNode{
-		Address:    mostReliable.Address,
-		Port:       mostReliable.Port,
-		Peers:      mostReliable.Peers,
-		LastTick:   mostReliable.LastTick,
-		LastUpdate: mostReliable.LastUpdate,
+	var rejectedNodes []rejectedNode
+	for _, outlier := range containerResponse.OutlierNodes {
+		r := rejectedNode{
+			Address:  outlier.Node.Address,
+			LastTick: outlier.Node.LastTick,
+			Reason:   outlier.Reason,
+		}
+		rejectedNodes = append(rejectedNodes, r)
 	}
 
 	response := statusResponse{
+		Sequence:                containerResponse.Sequence,
 		MaxTick:                 containerResponse.MaxTick,
 		LastUpdate:              containerResponse.LastUpdate,
 		NumberOfConfiguredNodes: h.Container.GetNumberOfConfiguredNodes(),
 		ReliableNodes:           reliableNodes,
-		MostReliableNode:        mostReliableResponse,
+		RejectedNodes:           rejectedNodes,
+		Degraded:                containerResponse.Degraded,
+	}
+	if mostReliableNode := h.Container.SelectMostReliableNode(containerResponse); mostReliableNode != nil {
+		mostReliableResponse := newReliableNode(mostReliableNode)
+		response.MostReliableNode = &mostReliableResponse
+	}
+	if stall := containerResponse.Stall; stall.Stalled {
+		response.Stalled = true
+		response.StalledSince = stall.Since.Unix()
+		response.StallReason = stall.Reason
+	}
+	// keep the stall and degraded state, if no node is reliable
+	statusCode := http.StatusOK
+	if len(reliableNodes) == 0 {
+		response.Message = "No online or reliable nodes found."
+		statusCode = http.StatusServiceUnavailable
 	}
 
 	data, err := json.Marshal(response)
@@ -92,7 +221,7 @@ func (h *PeersHandler) HandleStatus(w http.ResponseWriter, _ *http.Request) {
 	}
 
 	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(statusCode)
 	_, err = w.Write(data)
 	if err != nil {
 		log.Printf("Failed to write response for status request. Err: %v\n", err)
@@ -102,10 +231,15 @@ func (h *PeersHandler) HandleStatus(w http.ResponseWriter, _ *http.Request) {
 
 func (h *PeersHandler) HandleMaxTick(writer http.ResponseWriter, _ *http.Request) {
 
-	maxTick := h.Container.GetResponse().MaxTick
+	containerResponse := h.Container.GetResponse()
+
+	if containerResponse.Initializing {
+		writeNotReady(writer)
+		return
+	}
 
 	response := maxTickResponse{
-		maxTick,
+		containerResponse.MaxTick,
 	}
 
 	data, err := json.Marshal(response)
@@ -128,7 +262,9 @@ func (h *PeersHandler) HandleMaxTick(writer http.ResponseWriter, _ *http.Request
 
 func (h *PeersHandler) GetReliableNodesWithMinimumTick(w http.ResponseWriter, r *http.Request) {
 	var mtr struct {
-		MinimumTick uint32 `json:"minimum_tick"`
+		MinimumTick uint32  `json:"minimum_tick"`
+		Sort        string  `json:"sort"`
+		Sequence    *uint64 `json:"sequence"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&mtr)
@@ -141,9 +277,37 @@ func (h *PeersHandler) GetReliableNodesWithMinimumTick(w http.ResponseWriter, r
 		return
 	}
 
-	reliableNodes := h.Container.GetReliableNodesWithMinimumTick(mtr.MinimumTick)
+	snapshot := h.Container.GetSnapshot()
+	if mtr.Sequence != nil {
+		var ok bool
+		snapshot, ok = h.Container.GetSnapshotBySequence(*mtr.Sequence)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, err := w.Write([]byte(fmt.Sprintf("Snapshot %d is not available.", *mtr.Sequence)))
+			if err != nil {
+				log.Printf("Failed to respond to request: %v\n", err)
+			}
+			return
+		}
+	}
+
+	sortedNodes, err := sortReliableNodes(snapshot.ReliableNodesWithMinimumTick(mtr.MinimumTick), mtr.Sort)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	reliableNodes := make([]minimumTickNode, 0, len(sortedNodes))
+	for _, relNode := range sortedNodes {
+		reliableNodes = append(reliableNodes, newMinimumTickNode(relNode))
+	}
 
 	responseData := reliablePeersAtMinimumTickResponse{
+		Sequence:             snapshot.Sequence,
 		RequestedMinimumTick: mtr.MinimumTick,
 		ReliableNodes:        reliableNodes,
 	}
@@ -159,3 +323,25 @@ func (h *PeersHandler) GetReliableNodesWithMinimumTick(w http.ResponseWriter, r
 		return
 	}
 }
+
+func writeNotReady(w http.ResponseWriter) {
+	data, err := json.Marshal(notReadyResponse{
+		Status:  "initializing",
+		Message: "Waiting for the first successful refresh.",
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Printf("Failed to write not ready response. Err: %v\n", err)
+	}
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/google/go-cmp/cmp"
@@ -9,6 +10,7 @@ import (
 	"github.com/qubic/go-qubic-nodes/node"
 	"github.com/stretchr/testify/require"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -35,24 +37,21 @@ func TestHandler_whenStatus_thenReturnNumberOfConfiguredNodes(t *testing.T) {
 		LastUpdateSuccess: true,
 	}
 
-	peerManager := node.NewPeerManager([]string{node1.Address, node2.Address}, &node.NoPeerDiscovery{}, "12345", time.Second)
+	peerManager := node.NewPeerManager([]string{node1.Address, node2.Address}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
 
 	var container = node.Container{
 		PeerManager:        peerManager,
 		TickErrorThreshold: 3,
 		ReliableTickRange:  4,
-		OnlineNodes:        nil,
-		MaxTick:            123,
-		LastUpdate:         1500000000,
-		ReliableNodes:      []*node.Node{&node1},
-		MostReliableNode:   &node1,
 	}
+	container.Set(nil, 123, 1500000000, []*node.Node{&node1}, &node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
 
 	handler := PeersHandler{
 		Container: &container,
 	}
 
 	expectedResponse := `{
+		"sequence": 1,
 		"max_tick": 123,
 		"last_update": 1500000000,
 		"number_of_configured_nodes": 2,
@@ -65,7 +64,9 @@ func TestHandler_whenStatus_thenReturnNumberOfConfiguredNodes(t *testing.T) {
 				"3.4.5.6"
 			  ],
 			  "last_tick": 123,
-			  "last_update": 1500000000
+			  "last_update": 1500000000,
+			  "connect_latency_ms": 0,
+			  "tick_info_latency_ms": 0
 			}
 		],
 		"most_reliable_node": {
@@ -76,8 +77,12 @@ func TestHandler_whenStatus_thenReturnNumberOfConfiguredNodes(t *testing.T) {
 				"3.4.5.6"
 			],
 			"last_tick": 123,
-			"last_update": 1500000000
-		}
+			"last_update": 1500000000,
+			"connect_latency_ms": 0,
+			"tick_info_latency_ms": 0
+		},
+		"degraded": false,
+		"stalled": false
 	}`
 
 	resp := makeStatusCall(handler)
@@ -88,6 +93,88 @@ func TestHandler_whenStatus_thenReturnNumberOfConfiguredNodes(t *testing.T) {
 	require.JSONEq(t, expectedResponse, string(data))
 }
 
+func TestHandler_whenStatusWithOutliers_thenReturnRejectedNodes(t *testing.T) {
+
+	var node1 = node.Node{
+		Address:  "1.2.3.4",
+		Port:     "12345",
+		LastTick: 123,
+	}
+
+	var outlier = node.Node{
+		Address:  "6.6.6.6",
+		Port:     "12345",
+		LastTick: 9999,
+	}
+
+	var container = node.Container{
+		PeerManager: node.NewPeerManager([]string{node1.Address}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second),
+	}
+	container.Set(nil, 123, 0, []*node.Node{&node1}, &node1, []*node.TickOutlier{{Node: &outlier, Reason: "too far ahead"}}, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	handler := PeersHandler{
+		Container: &container,
+	}
+
+	resp := makeStatusCall(handler)
+	require.Equal(t, 200, resp.StatusCode, "Unexpected http status")
+
+	var status statusResponse
+	err := json.NewDecoder(resp.Body).Decode(&status)
+	require.NoError(t, err)
+	require.Equal(t, []rejectedNode{{Address: "6.6.6.6", LastTick: 9999, Reason: "too far ahead"}}, status.RejectedNodes)
+}
+
+func TestHandler_whenInitializing_thenReturnNotReady(t *testing.T) {
+	peerManager := node.NewPeerManager([]string{"1.2.3.4"}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
+	container := node.NewNodeContainer(peerManager, 50, 30, node.NewQuorumCount(1), nil, 0)
+	handler := PeersHandler{Container: container}
+
+	expectedResponse := `{"status": "initializing", "message": "Waiting for the first successful refresh."}`
+
+	resp := makeStatusCall(handler)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, expectedResponse, string(data))
+
+	rec := httptest.NewRecorder()
+	handler.HandleMaxTick(rec, nil)
+	resp = rec.Result()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	data, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, expectedResponse, string(data))
+}
+
+func TestHandler_whenNoReliableNodes_thenReturnStall(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, listener.Close()) // refuse connections
+
+	peerManager := node.NewPeerManager([]string{"127.0.0.1"}, &node.NoPeerDiscovery{}, node.NewConnectionPool(port, time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
+	container := &node.Container{PeerManager: peerManager, MaxTickQuorum: node.NewQuorumCount(1), StallTimeout: time.Millisecond}
+	require.Error(t, container.Update(context.Background()))
+	time.Sleep(2 * time.Millisecond)
+	require.Error(t, container.Update(context.Background()))
+	handler := PeersHandler{Container: container}
+
+	resp := makeStatusCall(handler)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var status statusResponse
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	require.NoError(t, err)
+	require.True(t, status.Stalled)
+	require.Equal(t, node.StallReasonConnectivity, status.StallReason)
+	require.NotZero(t, status.StalledSince)
+	require.Empty(t, status.ReliableNodes)
+	require.Nil(t, status.MostReliableNode)
+	require.Equal(t, "No online or reliable nodes found.", status.Message)
+}
+
 func TestPeersHandler_GetReliableNodesWithMinimumTick(t *testing.T) {
 	testData := []struct {
 		name                  string
@@ -190,14 +277,18 @@ func TestPeersHandler_GetReliableNodesWithMinimumTick(t *testing.T) {
 
 		return func(t *testing.T) {
 			container := &node.Container{}
-			container.ReliableNodes = nodes
+			container.Set(nil, 0, 0, nodes, nil, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
 
 			handler := PeersHandler{Container: container}
 			resp, err := makeGetReliableNodesWithMinimumTickCall(handler, minimumTick)
 			require.NoError(t, err, "making reliable nodes call")
 			expectedResponse := reliablePeersAtMinimumTickResponse{
+				Sequence:             1,
 				RequestedMinimumTick: minimumTick,
-				ReliableNodes:        expectedReliablePeers,
+				ReliableNodes:        []minimumTickNode{},
+			}
+			for _, expected := range expectedReliablePeers {
+				expectedResponse.ReliableNodes = append(expectedResponse.ReliableNodes, newMinimumTickNode(expected))
 			}
 
 			diff := cmp.Diff(expectedResponse, resp)
@@ -210,17 +301,136 @@ func TestPeersHandler_GetReliableNodesWithMinimumTick(t *testing.T) {
 	}
 }
 
+func TestHandler_whenSortByLatency_thenFastestNodeFirst(t *testing.T) {
+	slow := &node.Node{Address: "1.2.3.4", LastTick: 123, ConnectLatency: 30 * time.Millisecond, TickInfoLatency: 20 * time.Millisecond}
+	fast := &node.Node{Address: "2.3.4.5", LastTick: 123, ConnectLatency: 10 * time.Millisecond, TickInfoLatency: 1500 * time.Microsecond}
+
+	var container = node.Container{
+		PeerManager: node.NewPeerManager([]string{slow.Address, fast.Address}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second),
+	}
+	container.Set(nil, 123, 0, []*node.Node{slow, fast}, slow, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	handler := PeersHandler{Container: &container}
+
+	resp := makeStatusCallWithQuery(handler, "?sort=latency")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var status statusResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.Len(t, status.ReliableNodes, 2)
+	require.Equal(t, "2.3.4.5", status.ReliableNodes[0].Address)
+	require.Equal(t, 10.0, status.ReliableNodes[0].ConnectLatencyMs)
+	require.Equal(t, 1.5, status.ReliableNodes[0].TickInfoLatencyMs)
+	require.Equal(t, "1.2.3.4", status.MostReliableNode.Address)
+
+	reliable, err := makeGetReliableNodesCall(handler, `{"minimum_tick": 100, "sort": "latency"}`)
+	require.NoError(t, err)
+	require.Equal(t, []minimumTickNode{newMinimumTickNode(fast), newMinimumTickNode(slow)}, reliable.ReliableNodes)
+	require.Equal(t, 10.0, reliable.ReliableNodes[0].ConnectLatencyMs)
+	require.Equal(t, 1.5, reliable.ReliableNodes[0].TickInfoLatencyMs)
+	require.Equal(t, []*node.Node{slow, fast}, container.GetResponse().ReliableNodes, "container order must not change")
+
+	resp = makeStatusCallWithQuery(handler, "?sort=unknown")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPeersHandler_GetReliableNodesWithMinimumTick_keepsFieldNames(t *testing.T) {
+	reliable := &node.Node{Address: "1.2.3.4", Port: "21841", LastTick: 123, LastUpdate: 1500000000, LastUpdateSuccess: true, ConnectLatency: 10 * time.Millisecond, TickInfoLatency: 1500 * time.Microsecond}
+	container := &node.Container{}
+	container.Set(nil, 123, 0, []*node.Node{reliable}, reliable, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	handler := PeersHandler{Container: container}
+
+	rec := httptest.NewRecorder()
+	handler.GetReliableNodesWithMinimumTick(rec, httptest.NewRequest("POST", "/reliable-nodes", bytes.NewBufferString(`{"minimum_tick": 100}`)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{
+		"sequence": 1,
+		"requested_minimum_tick": 100,
+		"reliable_nodes": [{
+			"Address": "1.2.3.4",
+			"Port": "21841",
+			"Peers": null,
+			"LastTick": 123,
+			"LastUpdate": 1500000000,
+			"LastUpdateSuccess": true,
+			"connect_latency_ms": 10,
+			"tick_info_latency_ms": 1.5
+		}]
+	}`, rec.Body.String())
+}
+
+func TestHandler_whenRoundRobinSelection_thenSelectPerRequest(t *testing.T) {
+	first := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	second := &node.Node{Address: "2.3.4.5", LastTick: 100}
+	container := &node.Container{
+		PeerManager:     node.NewPeerManager([]string{first.Address, second.Address}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second),
+		SelectionPolicy: node.NewRoundRobinSelection(2),
+	}
+	container.Set(nil, 100, 0, []*node.Node{first, second}, first, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	handler := PeersHandler{Container: container}
+
+	var selected []string
+	for range 3 {
+		resp := makeStatusCall(handler)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var status statusResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		selected = append(selected, status.MostReliableNode.Address)
+	}
+	require.Equal(t, []string{"1.2.3.4", "2.3.4.5", "1.2.3.4"}, selected)
+}
+
+func TestHandler_whenSequence_thenReturnThatSnapshot(t *testing.T) {
+	first := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	second := &node.Node{Address: "1.2.3.4", LastTick: 110}
+	var container = node.Container{
+		PeerManager: node.NewPeerManager([]string{first.Address}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second),
+	}
+	container.Set(nil, 100, 0, []*node.Node{first}, first, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	container.Set(nil, 110, 0, []*node.Node{second}, second, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	handler := PeersHandler{Container: &container}
+
+	resp := makeStatusCall(handler)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var status statusResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.Equal(t, uint64(2), status.Sequence)
+	require.Equal(t, uint32(110), status.MaxTick)
+
+	resp = makeStatusCallWithQuery(handler, "?sequence=1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.Equal(t, uint64(1), status.Sequence)
+	require.Equal(t, uint32(100), status.MaxTick)
+
+	reliable, err := makeGetReliableNodesCall(handler, `{"minimum_tick": 0, "sequence": 1}`)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), reliable.Sequence)
+	require.Equal(t, []minimumTickNode{newMinimumTickNode(first)}, reliable.ReliableNodes)
+
+	resp = makeStatusCallWithQuery(handler, "?sequence=3")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = makeStatusCallWithQuery(handler, "?sequence=latest")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
 func makeStatusCall(handler PeersHandler) *http.Response {
+	return makeStatusCallWithQuery(handler, "")
+}
+
+func makeStatusCallWithQuery(handler PeersHandler, query string) *http.Response {
 	rec := httptest.NewRecorder()
-	handler.HandleStatus(rec, nil)
+	handler.HandleStatus(rec, httptest.NewRequest("GET", "/status"+query, nil))
 	resp := rec.Result()
 	defer resp.Body.Close()
 	return resp
 }
 
 func makeGetReliableNodesWithMinimumTickCall(handler PeersHandler, minimumTick uint32) (reliablePeersAtMinimumTickResponse, error) {
+	return makeGetReliableNodesCall(handler, `{"minimum_tick": `+fmt.Sprintf("%d", minimumTick)+`}`)
+}
+
+func makeGetReliableNodesCall(handler PeersHandler, body string) (reliablePeersAtMinimumTickResponse, error) {
 	rec := httptest.NewRecorder()
-	body := `{"minimum_tick": ` + fmt.Sprintf("%d", minimumTick) + `}`
 	req := httptest.NewRequest("POST", "/reliable-nodes", bytes.NewBuffer([]byte(body)))
 	defer req.Body.Close()
 
@@ -0,0 +1,179 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/qubic/go-qubic-nodes/node"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// time range returned, if no start is given
+const defaultHistoryRange = time.Hour
+
+type HistoryHandler struct {
+	History *node.TickHistory
+}
+
+type historyRange struct {
+	from time.Time
+	to   time.Time
+	step time.Duration
+}
+
+type maxTickHistoryResponse struct {
+	From   int64                `json:"from"`
+	To     int64                `json:"to"`
+	Step   int64                `json:"step"`
+	Points []maxTickHistoryItem `json:"points"`
+}
+
+type maxTickHistoryItem struct {
+	Timestamp     int64  `json:"timestamp"`
+	MaxTick       uint32 `json:"max_tick"`
+	ReliableNodes int    `json:"reliable_nodes"`
+}
+
+type nodeHistoryResponse struct {
+	Address string            `json:"address"`
+	From    int64             `json:"from"`
+	To      int64             `json:"to"`
+	Step    int64             `json:"step"`
+	Points  []nodeHistoryItem `json:"points"`
+}
+
+type nodeHistoryItem struct {
+	Timestamp int64  `json:"timestamp"`
+	Tick      uint32 `json:"tick"`
+	MaxTick   uint32 `json:"max_tick"`
+	Lag       uint32 `json:"lag"`
+}
+
+// HandleMaxTickHistory returns the max tick and the number of reliable nodes of the refreshes in the given time range.
+func (h *HistoryHandler) HandleMaxTickHistory(w http.ResponseWriter, r *http.Request) {
+	query, err := parseHistoryRange(r, time.Now())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	response := maxTickHistoryResponse{
+		From:   query.from.Unix(),
+		To:     query.to.Unix(),
+		Step:   int64(query.step / time.Second),
+		Points: []maxTickHistoryItem{},
+	}
+	for _, record := range h.History.Range(query.from, query.to, query.step) {
+		response.Points = append(response.Points, maxTickHistoryItem{
+			Timestamp:     record.At.Unix(),
+			MaxTick:       record.MaxTick,
+			ReliableNodes: record.ReliableNodes,
+		})
+	}
+	writeHistoryResponse(w, response)
+}
+
+// HandleNodeHistory returns the ticks of a node in the given time range. Refreshes that did not reach the node are
+// left out.
+func (h *HistoryHandler) HandleNodeHistory(w http.ResponseWriter, r *http.Request) {
+	query, err := parseHistoryRange(r, time.Now())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	address := r.PathValue("address")
+	ticks, ok := h.History.NodeTicks(address, query.from, query.to, query.step)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, err := w.Write([]byte("No history for node " + address + "."))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	response := nodeHistoryResponse{
+		Address: address,
+		From:    query.from.Unix(),
+		To:      query.to.Unix(),
+		Step:    int64(query.step / time.Second),
+		Points:  []nodeHistoryItem{},
+	}
+	for _, tick := range ticks {
+		item := nodeHistoryItem{
+			Timestamp: tick.At.Unix(),
+			Tick:      tick.Tick,
+			MaxTick:   tick.MaxTick,
+		}
+		if tick.MaxTick > tick.Tick {
+			item.Lag = tick.MaxTick - tick.Tick
+		}
+		response.Points = append(response.Points, item)
+	}
+	writeHistoryResponse(w, response)
+}
+
+// parseHistoryRange parses `from` and `to` as unix timestamps and `step` as duration (e.g. `5m`). By default, the last
+// hour is returned without downsampling.
+func parseHistoryRange(r *http.Request, now time.Time) (historyRange, error) {
+	values := r.URL.Query()
+	query := historyRange{to: now}
+
+	if value := values.Get("to"); value != "" {
+		to, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return historyRange{}, errors.Errorf("invalid to: %s", value)
+		}
+		query.to = time.Unix(to, 0)
+	}
+	query.from = query.to.Add(-defaultHistoryRange)
+	if value := values.Get("from"); value != "" {
+		from, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return historyRange{}, errors.Errorf("invalid from: %s", value)
+		}
+		query.from = time.Unix(from, 0)
+	}
+	if query.from.After(query.to) {
+		return historyRange{}, errors.New("from must not be after to")
+	}
+
+	if value := values.Get("step"); value != "" {
+		step, err := time.ParseDuration(value)
+		if err != nil || step < time.Second {
+			return historyRange{}, errors.Errorf("invalid step: %s, minimum is 1s", value)
+		}
+		query.step = step
+	}
+	return query, nil
+}
+
+func writeBadRequest(w http.ResponseWriter, err error) {
+	w.WriteHeader(http.StatusBadRequest)
+	_, err = w.Write([]byte(err.Error()))
+	if err != nil {
+		log.Printf("Failed to respond to request: %v\n", err)
+	}
+}
+
+func writeHistoryResponse(w http.ResponseWriter, response any) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Printf("Failed to write response for history request. Err: %v\n", err)
+	}
+}
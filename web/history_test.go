@@ -0,0 +1,87 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/qubic/go-qubic-nodes/node"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newHistoryTestHandler(t *testing.T) (HistoryHandler, time.Time) {
+	history := node.NewTickHistory(0)
+	start := time.Unix(1714654600, 0)
+	for i := 0; i < 6; i++ {
+		require.NoError(t, history.Record(node.TickRecord{
+			At:            start.Add(time.Duration(i) * 10 * time.Second),
+			MaxTick:       uint32(1000 + i),
+			ReliableNodes: 2,
+			NodeTicks:     map[string]uint32{"1.2.3.4": uint32(1000 + i), "2.3.4.5": uint32(998 + i)},
+		}))
+	}
+	return HistoryHandler{History: history}, start
+}
+
+func getHistory(handler func(http.ResponseWriter, *http.Request), pattern, target string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handler)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+	return rec
+}
+
+func TestHistoryHandler_MaxTick(t *testing.T) {
+	handler, start := newHistoryTestHandler(t)
+
+	rec := getHistory(handler.HandleMaxTickHistory, "GET /history/max-tick",
+		fmt.Sprintf("/history/max-tick?from=%d&to=%d&step=30s", start.Unix(), start.Unix()+60))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, fmt.Sprintf(`{
+		"from": %d,
+		"to": %d,
+		"step": 30,
+		"points": [
+			{"timestamp": %d, "max_tick": 1002, "reliable_nodes": 2},
+			{"timestamp": %d, "max_tick": 1005, "reliable_nodes": 2}
+		]
+	}`, start.Unix(), start.Unix()+60, start.Unix(), start.Unix()+30), rec.Body.String())
+
+	rec = getHistory(handler.HandleMaxTickHistory, "GET /history/max-tick",
+		fmt.Sprintf("/history/max-tick?from=%d&to=%d", start.Unix()+10, start.Unix()+30))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response maxTickHistoryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Points, 2)
+	require.Equal(t, uint32(1001), response.Points[0].MaxTick)
+}
+
+func TestHistoryHandler_MaxTick_invalidQuery(t *testing.T) {
+	handler, _ := newHistoryTestHandler(t)
+
+	for _, query := range []string{"from=abc", "to=abc", "from=20&to=10", "step=abc", "step=100ms"} {
+		rec := getHistory(handler.HandleMaxTickHistory, "GET /history/max-tick", "/history/max-tick?"+query)
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestHistoryHandler_Node(t *testing.T) {
+	handler, start := newHistoryTestHandler(t)
+
+	rec := getHistory(handler.HandleNodeHistory, "GET /history/nodes/{address}",
+		fmt.Sprintf("/history/nodes/2.3.4.5?from=%d&to=%d&step=1m", start.Unix(), start.Unix()+60))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, fmt.Sprintf(`{
+		"address": "2.3.4.5",
+		"from": %d,
+		"to": %d,
+		"step": 60,
+		"points": [{"timestamp": %d, "tick": 1003, "max_tick": 1005, "lag": 2}]
+	}`, start.Unix(), start.Unix()+60, start.Unix()), rec.Body.String())
+
+	rec = getHistory(handler.HandleNodeHistory, "GET /history/nodes/{address}", "/history/nodes/6.6.6.6")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,394 @@
+package web
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/qubic/go-node-connector/types"
+	"github.com/qubic/go-qubic-nodes/node"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNodesLimit = 100
+	maxNodesLimit     = 1000
+)
+
+var nodeStates = []string{node.NodeStateReliable, node.NodeStateOnline, node.NodeStateExcluded, node.NodeStateOffline, node.NodeStateSkipped}
+var nodeSources = []string{node.NodeSourceConfigured, node.NodeSourceDiscovered}
+
+// sortKeys return the primary sort key of a node, ties are broken by the address
+var sortKeys = map[string]func(info node.NodeInfo) float64{
+	"address": func(node.NodeInfo) float64 { return 0 },
+	"tick":    func(info node.NodeInfo) float64 { return -float64(info.Node.LastTick) },
+	"lag":     func(info node.NodeInfo) float64 { return float64(info.Lag) },
+	"latency": func(info node.NodeInfo) float64 {
+		if info.Node.TickInfoLatency == 0 {
+			return math.MaxFloat64
+		}
+		return milliseconds(info.Node.TickInfoLatency)
+	},
+	"score": func(info node.NodeInfo) float64 { return -info.Health.Score },
+}
+
+type nodesResponse struct {
+	MaxTick    uint32     `json:"max_tick"`
+	Total      int        `json:"total"`
+	Nodes      []nodeItem `json:"nodes"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type nodeItem struct {
+	Address           string  `json:"address"`
+	State             string  `json:"state"`
+	Source            string  `json:"source"`
+	Reason            string  `json:"reason,omitempty"`
+	LastTick          uint32  `json:"last_tick"`
+	Lag               uint32  `json:"lag"`
+	Epoch             uint16  `json:"epoch"`
+	LastUpdate        int64   `json:"last_update"`
+	TickInfoLatencyMs float64 `json:"tick_info_latency_ms"`
+	Score             float64 `json:"score"`
+	SuccessRatio      float64 `json:"success_ratio"`
+	LastErrorType     string  `json:"last_error_type,omitempty"`
+}
+
+type nodesQuery struct {
+	states  []string
+	sources []string
+	minTick *uint32
+	maxLag  *uint32
+	sort    string
+	limit   int
+	cursor  *nodesCursor
+}
+
+// nodesCursor points behind the last returned node. It is only valid for the sort order it was created for.
+type nodesCursor struct {
+	Sort    string  `json:"s"`
+	Key     float64 `json:"k"`
+	Address string  `json:"a"`
+}
+
+func (c nodesCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeNodesCursor(value string) (*nodesCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor nodesCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// HandleNodes lists all known nodes. The nodes can be filtered by state, source, minimum tick and maximum lag, are
+// sorted by address, tick, lag, latency or score and are paginated with a cursor.
+func (h *PeersHandler) HandleNodes(w http.ResponseWriter, r *http.Request) {
+	query, err := parseNodesQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	sortKey := sortKeys[query.sort]
+	var nodes []node.NodeInfo
+	for _, info := range h.Container.GetNodes() {
+		if query.matches(info) {
+			nodes = append(nodes, info)
+		}
+	}
+	compare := func(aKey float64, aAddress string, bKey float64, bAddress string) int {
+		if c := cmp.Compare(aKey, bKey); c != 0 {
+			return c
+		}
+		return cmp.Compare(aAddress, bAddress)
+	}
+	slices.SortFunc(nodes, func(a, b node.NodeInfo) int {
+		return compare(sortKey(a), a.Node.Address, sortKey(b), b.Node.Address)
+	})
+
+	start := 0
+	if query.cursor != nil {
+		start, _ = slices.BinarySearchFunc(nodes, query.cursor, func(info node.NodeInfo, cursor *nodesCursor) int {
+			// position of the first node after the cursor
+			if compare(sortKey(info), info.Node.Address, cursor.Key, cursor.Address) <= 0 {
+				return -1
+			}
+			return 1
+		})
+	}
+	end := min(start+query.limit, len(nodes))
+
+	response := nodesResponse{
+		MaxTick: h.Container.GetResponse().MaxTick,
+		Total:   len(nodes),
+		Nodes:   make([]nodeItem, 0, end-start),
+	}
+	for _, info := range nodes[start:end] {
+		response.Nodes = append(response.Nodes, newNodeItem(info))
+	}
+	if end < len(nodes) {
+		last := nodes[end-1]
+		response.NextCursor = nodesCursor{Sort: query.sort, Key: sortKey(last), Address: last.Node.Address}.encode()
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Printf("Failed to write response for nodes request. Err: %v\n", err)
+	}
+}
+
+func newNodeItem(info node.NodeInfo) nodeItem {
+	return nodeItem{
+		Address:           info.Node.Address,
+		State:             info.State,
+		Source:            info.Source,
+		Reason:            info.Reason,
+		LastTick:          info.Node.LastTick,
+		Lag:               info.Lag,
+		Epoch:             info.Node.Epoch,
+		LastUpdate:        info.Node.LastUpdate,
+		TickInfoLatencyMs: milliseconds(info.Node.TickInfoLatency),
+		Score:             info.Health.Score,
+		SuccessRatio:      info.Health.SuccessRatio,
+		LastErrorType:     info.Health.LastErrorType,
+	}
+}
+
+func parseNodesQuery(r *http.Request) (nodesQuery, error) {
+	values := r.URL.Query()
+	query := nodesQuery{
+		sort:  "address",
+		limit: defaultNodesLimit,
+	}
+
+	var err error
+	if query.states, err = parseList(values.Get("state"), nodeStates, "state"); err != nil {
+		return nodesQuery{}, err
+	}
+	if query.sources, err = parseList(values.Get("source"), nodeSources, "source"); err != nil {
+		return nodesQuery{}, err
+	}
+	if query.minTick, err = parseUint32(values.Get("min_tick"), "min_tick"); err != nil {
+		return nodesQuery{}, err
+	}
+	if query.maxLag, err = parseUint32(values.Get("max_lag"), "max_lag"); err != nil {
+		return nodesQuery{}, err
+	}
+	if value := values.Get("sort"); value != "" {
+		if _, ok := sortKeys[value]; !ok {
+			return nodesQuery{}, errors.Errorf("unknown sort order: %s", value)
+		}
+		query.sort = value
+	}
+	if value := values.Get("limit"); value != "" {
+		query.limit, err = strconv.Atoi(value)
+		if err != nil || query.limit < 1 || query.limit > maxNodesLimit {
+			return nodesQuery{}, errors.Errorf("limit must be between 1 and %d", maxNodesLimit)
+		}
+	}
+	if value := values.Get("cursor"); value != "" {
+		if query.cursor, err = decodeNodesCursor(value); err != nil {
+			return nodesQuery{}, err
+		}
+		if query.cursor.Sort != query.sort {
+			return nodesQuery{}, errors.New("cursor does not match sort order")
+		}
+	}
+	return query, nil
+}
+
+func parseList(value string, allowed []string, name string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	list := strings.Split(value, ",")
+	for _, item := range list {
+		if !slices.Contains(allowed, item) {
+			return nil, errors.Errorf("unknown %s: %s", name, item)
+		}
+	}
+	return list, nil
+}
+
+func parseUint32(value string, name string) (*uint32, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, errors.Errorf("invalid %s: %s", name, value)
+	}
+	result := uint32(parsed)
+	return &result, nil
+}
+
+// matches returns true, if the node passes all filters. Offline nodes have no tick and are filtered out by a minimum
+// tick or maximum lag.
+func (q nodesQuery) matches(info node.NodeInfo) bool {
+	if q.states != nil && !slices.Contains(q.states, info.State) {
+		return false
+	}
+	if q.sources != nil && !slices.Contains(q.sources, info.Source) {
+		return false
+	}
+	if q.minTick != nil && info.Node.LastTick < *q.minTick {
+		return false
+	}
+	if q.maxLag != nil && (info.State == node.NodeStateOffline || info.State == node.NodeStateSkipped || info.Lag > *q.maxLag) {
+		return false
+	}
+	return true
+}
+
+type nodeDetailResponse struct {
+	Address           string            `json:"address"`
+	Port              string            `json:"port"`
+	State             string            `json:"state"`
+	Source            string            `json:"source"`
+	Reason            string            `json:"reason,omitempty"`
+	MaxTick           uint32            `json:"max_tick"`
+	LastTick          uint32            `json:"last_tick"`
+	Lag               uint32            `json:"lag"`
+	Epoch             uint16            `json:"epoch"`
+	LastUpdate        int64             `json:"last_update"`
+	LastSuccess       int64             `json:"last_success,omitempty"`
+	LastFailure       int64             `json:"last_failure,omitempty"`
+	LastError         string            `json:"last_error,omitempty"`
+	LastErrorType     string            `json:"last_error_type,omitempty"`
+	ConnectLatencyMs  float64           `json:"connect_latency_ms"`
+	TickInfoLatencyMs float64           `json:"tick_info_latency_ms"`
+	Health            nodeHealth        `json:"health"`
+	LatencyHistory    []latencySample   `json:"latency_history"`
+	ReportedBy        []string          `json:"reported_by"`
+	Peers             types.PublicPeers `json:"peers"`
+}
+
+type nodeHealth struct {
+	Samples       int     `json:"samples"`
+	SuccessRatio  float64 `json:"success_ratio"`
+	ReliableRatio float64 `json:"reliable_ratio"`
+	LatencyP50Ms  float64 `json:"latency_p50_ms"`
+	LatencyP90Ms  float64 `json:"latency_p90_ms"`
+	LatencyP99Ms  float64 `json:"latency_p99_ms"`
+	AverageLag    float64 `json:"average_lag"`
+	MaxLag        uint32  `json:"max_lag"`
+	Score         float64 `json:"score"`
+}
+
+type latencySample struct {
+	At        int64   `json:"at"`
+	Success   bool    `json:"success"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// HandleNode returns everything known about a single node, including the recent polls and the online nodes that
+// report it as peer.
+func (h *PeersHandler) HandleNode(w http.ResponseWriter, r *http.Request) {
+	detail, ok := h.Container.GetNode(r.PathValue("address"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, err := w.Write([]byte("Unknown node."))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	health := detail.Health
+	response := nodeDetailResponse{
+		Address:           detail.Node.Address,
+		Port:              detail.Node.Port,
+		State:             detail.State,
+		Source:            detail.Source,
+		Reason:            detail.Reason,
+		MaxTick:           detail.MaxTick,
+		LastTick:          detail.Node.LastTick,
+		Lag:               detail.Lag,
+		Epoch:             detail.Node.Epoch,
+		LastUpdate:        detail.Node.LastUpdate,
+		LastSuccess:       unixOrZero(health.LastSeen),
+		LastFailure:       unixOrZero(health.LastFailure),
+		LastError:         health.LastError,
+		LastErrorType:     health.LastErrorType,
+		ConnectLatencyMs:  milliseconds(detail.Node.ConnectLatency),
+		TickInfoLatencyMs: milliseconds(detail.Node.TickInfoLatency),
+		Health: nodeHealth{
+			Samples:       health.Samples,
+			SuccessRatio:  health.SuccessRatio,
+			ReliableRatio: health.ReliableRatio,
+			LatencyP50Ms:  milliseconds(health.LatencyP50),
+			LatencyP90Ms:  milliseconds(health.LatencyP90),
+			LatencyP99Ms:  milliseconds(health.LatencyP99),
+			AverageLag:    health.AverageLag,
+			MaxLag:        health.MaxLag,
+			Score:         health.Score,
+		},
+		LatencyHistory: make([]latencySample, 0, len(detail.Polls)),
+		ReportedBy:     detail.ReportedBy,
+		Peers:          detail.Node.Peers,
+	}
+	if response.Peers == nil {
+		response.Peers = types.PublicPeers{}
+	}
+	for _, poll := range detail.Polls {
+		response.LatencyHistory = append(response.LatencyHistory, latencySample{
+			At:        unixOrZero(poll.At),
+			Success:   poll.Success,
+			LatencyMs: milliseconds(poll.Latency),
+		})
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Printf("Failed to write response for node request. Err: %v\n", err)
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,176 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/qubic/go-qubic-nodes/node"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newNodesTestHandler() PeersHandler {
+	reliable := &node.Node{Address: "1.2.3.4", LastTick: 100, TickInfoLatency: 20 * time.Millisecond}
+	fast := &node.Node{Address: "2.3.4.5", LastTick: 99, TickInfoLatency: 5 * time.Millisecond}
+	lagging := &node.Node{Address: "3.4.5.6", LastTick: 50, TickInfoLatency: 10 * time.Millisecond}
+	outlier := &node.Node{Address: "4.5.6.7", LastTick: 9999}
+
+	peerManager := node.NewPeerManager([]string{"1.2.3.4", "2.3.4.5", "5.6.7.8"}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
+	peerManager.RestorePeers([]node.StoredPeer{{Address: "3.4.5.6"}, {Address: "4.5.6.7"}})
+
+	container := &node.Container{PeerManager: peerManager}
+	container.Set([]*node.Node{reliable, fast, lagging, outlier}, 100, 1500000000, []*node.Node{reliable, fast}, reliable,
+		[]*node.TickOutlier{{Node: outlier, Reason: "too far ahead"}}, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	return PeersHandler{Container: container}
+}
+
+func getNodes(t *testing.T, handler PeersHandler, query string) (int, nodesResponse) {
+	rec := httptest.NewRecorder()
+	handler.HandleNodes(rec, httptest.NewRequest("GET", "/nodes"+query, nil))
+	var response nodesResponse
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	}
+	return rec.Code, response
+}
+
+func nodeAddresses(response nodesResponse) []string {
+	var addresses []string
+	for _, item := range response.Nodes {
+		addresses = append(addresses, item.Address)
+	}
+	return addresses
+}
+
+func TestHandler_HandleNodes(t *testing.T) {
+	handler := newNodesTestHandler()
+
+	status, response := getNodes(t, handler, "")
+
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, uint32(100), response.MaxTick)
+	require.Equal(t, 5, response.Total)
+	require.Empty(t, response.NextCursor)
+	require.Equal(t, []nodeItem{
+		{Address: "1.2.3.4", State: "reliable", Source: "configured", LastTick: 100, LastUpdate: 0, TickInfoLatencyMs: 20},
+		{Address: "2.3.4.5", State: "reliable", Source: "configured", LastTick: 99, Lag: 1, TickInfoLatencyMs: 5},
+		{Address: "3.4.5.6", State: "online", Source: "discovered", LastTick: 50, Lag: 50, TickInfoLatencyMs: 10},
+		{Address: "4.5.6.7", State: "excluded", Source: "discovered", Reason: "too far ahead", LastTick: 9999},
+		{Address: "5.6.7.8", State: "offline", Source: "configured"},
+	}, response.Nodes)
+}
+
+func TestHandler_HandleNodes_filters(t *testing.T) {
+	handler := newNodesTestHandler()
+
+	testData := []struct {
+		query    string
+		expected []string
+	}{
+		{"?state=reliable,online", []string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}},
+		{"?source=discovered", []string{"3.4.5.6", "4.5.6.7"}},
+		{"?min_tick=99", []string{"1.2.3.4", "2.3.4.5", "4.5.6.7"}},
+		{"?max_lag=10", []string{"1.2.3.4", "2.3.4.5", "4.5.6.7"}},
+		{"?state=online,reliable&source=configured&max_lag=0", []string{"1.2.3.4"}},
+		{"?sort=latency", []string{"2.3.4.5", "3.4.5.6", "1.2.3.4", "4.5.6.7", "5.6.7.8"}},
+		{"?sort=tick", []string{"4.5.6.7", "1.2.3.4", "2.3.4.5", "3.4.5.6", "5.6.7.8"}},
+		{"?sort=lag&state=online,reliable", []string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}},
+	}
+	for _, test := range testData {
+		t.Run(test.query, func(t *testing.T) {
+			status, response := getNodes(t, handler, test.query)
+			require.Equal(t, http.StatusOK, status)
+			require.Equal(t, test.expected, nodeAddresses(response))
+		})
+	}
+}
+
+func TestHandler_HandleNodes_pagination(t *testing.T) {
+	handler := newNodesTestHandler()
+
+	var pages [][]string
+	query := "?sort=latency&limit=2"
+	for {
+		status, response := getNodes(t, handler, query)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 5, response.Total)
+		pages = append(pages, nodeAddresses(response))
+		if response.NextCursor == "" {
+			break
+		}
+		query = "?sort=latency&limit=2&cursor=" + response.NextCursor
+	}
+
+	require.Equal(t, [][]string{{"2.3.4.5", "3.4.5.6"}, {"1.2.3.4", "4.5.6.7"}, {"5.6.7.8"}}, pages)
+}
+
+func TestHandler_HandleNodes_invalidQuery(t *testing.T) {
+	handler := newNodesTestHandler()
+	_, firstPage := getNodes(t, handler, "?limit=1")
+
+	for _, query := range []string{"?state=unknown", "?source=unknown", "?min_tick=-1", "?max_lag=abc", "?sort=unknown",
+		"?limit=0", "?limit=1001", "?cursor=invalid", "?sort=tick&cursor=" + firstPage.NextCursor} {
+		t.Run(query, func(t *testing.T) {
+			status, _ := getNodes(t, handler, query)
+			require.Equal(t, http.StatusBadRequest, status)
+		})
+	}
+}
+
+func TestHandler_HandleNode(t *testing.T) {
+	node1 := &node.Node{Address: "1.2.3.4", Port: "21841", Peers: []string{"2.3.4.5", "3.4.5.6"}, LastTick: 100, Epoch: 111,
+		LastUpdate: 1500000000, ConnectLatency: 40 * time.Millisecond, TickInfoLatency: 10 * time.Millisecond}
+	node2 := &node.Node{Address: "2.3.4.5", Port: "21841", Peers: []string{"1.2.3.4"}, LastTick: 90}
+	peerManager := node.NewPeerManager([]string{"1.2.3.4", "2.3.4.5", "3.4.5.6"}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
+	container := &node.Container{PeerManager: peerManager}
+	container.Set([]*node.Node{node1, node2}, 100, 1500000000, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	handler := PeersHandler{Container: container}
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /nodes/{address}", handler.HandleNode)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/nodes/1.2.3.4", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{
+		"address": "1.2.3.4",
+		"port": "21841",
+		"state": "reliable",
+		"source": "configured",
+		"max_tick": 100,
+		"last_tick": 100,
+		"lag": 0,
+		"epoch": 111,
+		"last_update": 1500000000,
+		"connect_latency_ms": 40,
+		"tick_info_latency_ms": 10,
+		"health": {
+			"samples": 0,
+			"success_ratio": 0,
+			"reliable_ratio": 0,
+			"latency_p50_ms": 0,
+			"latency_p90_ms": 0,
+			"latency_p99_ms": 0,
+			"average_lag": 0,
+			"max_lag": 0,
+			"score": 0
+		},
+		"latency_history": [],
+		"reported_by": ["2.3.4.5"],
+		"peers": ["2.3.4.5", "3.4.5.6"]
+	}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/nodes/3.4.5.6", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var offline nodeDetailResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&offline))
+	require.Equal(t, "offline", offline.State)
+	require.Equal(t, []string{"1.2.3.4"}, offline.ReportedBy)
+	require.Empty(t, offline.Peers)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/nodes/9.9.9.9", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/qubic/go-qubic-nodes/node"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ProbesHandler answers liveness and readiness probes. The refresh loop counts as stuck, if no refresh finished within
+// MaxMissedRefreshes refresh intervals.
+type ProbesHandler struct {
+	Container          *node.Container
+	RefreshInterval    time.Duration
+	MaxMissedRefreshes int
+	MinReliableNodes   int
+}
+
+type probeResponse struct {
+	Status                string `json:"status"`
+	Reason                string `json:"reason,omitempty"`
+	LastRefresh           int64  `json:"last_refresh"`
+	LastSuccessfulRefresh int64  `json:"last_successful_refresh"`
+	ReliableNodes         int    `json:"reliable_nodes"`
+}
+
+// HandleLiveness reports if the refresh loop is still running.
+func (h *ProbesHandler) HandleLiveness(w http.ResponseWriter, _ *http.Request) {
+	h.writeProbe(w, h.checkLiveness(time.Now()))
+}
+
+// HandleReadiness reports if the service has recent data and enough reliable nodes to answer requests.
+func (h *ProbesHandler) HandleReadiness(w http.ResponseWriter, _ *http.Request) {
+	h.writeProbe(w, h.checkReadiness(time.Now()))
+}
+
+func (h *ProbesHandler) checkLiveness(now time.Time) string {
+	lastRefresh, _ := h.Container.GetRefreshTimes()
+	if now.Sub(lastRefresh) > h.maxRefreshAge() {
+		return fmt.Sprintf("no refresh finished since %s", lastRefresh.UTC().Format(time.RFC3339))
+	}
+	return ""
+}
+
+func (h *ProbesHandler) checkReadiness(now time.Time) string {
+	_, lastSuccess := h.Container.GetRefreshTimes()
+	if lastSuccess.IsZero() {
+		return "no successful refresh yet"
+	}
+	if now.Sub(lastSuccess) > h.maxRefreshAge() {
+		return fmt.Sprintf("no successful refresh since %s", lastSuccess.UTC().Format(time.RFC3339))
+	}
+	reliableNodes := len(h.Container.GetResponse().ReliableNodes)
+	if reliableNodes < h.MinReliableNodes {
+		return fmt.Sprintf("%d reliable nodes, at least %d required", reliableNodes, h.MinReliableNodes)
+	}
+	return ""
+}
+
+func (h *ProbesHandler) maxRefreshAge() time.Duration {
+	return time.Duration(h.MaxMissedRefreshes) * h.RefreshInterval
+}
+
+func (h *ProbesHandler) writeProbe(w http.ResponseWriter, reason string) {
+	lastRefresh, lastSuccess := h.Container.GetRefreshTimes()
+	response := probeResponse{
+		Status:        "ok",
+		Reason:        reason,
+		LastRefresh:   lastRefresh.Unix(),
+		ReliableNodes: len(h.Container.GetResponse().ReliableNodes),
+	}
+	if !lastSuccess.IsZero() {
+		response.LastSuccessfulRefresh = lastSuccess.Unix()
+	}
+	status := http.StatusOK
+	if reason != "" {
+		response.Status = "failing"
+		status = http.StatusServiceUnavailable
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Printf("Failed to write probe response. Err: %v\n", err)
+	}
+}
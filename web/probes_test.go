@@ -0,0 +1,55 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/qubic/go-qubic-nodes/node"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestProbesHandler() ProbesHandler {
+	peerManager := node.NewPeerManager([]string{"1.2.3.4"}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
+	return ProbesHandler{
+		Container:          node.NewNodeContainer(peerManager, 50, 30, node.NewQuorumCount(1), nil, 0),
+		RefreshInterval:    time.Minute,
+		MaxMissedRefreshes: 3,
+		MinReliableNodes:   1,
+	}
+}
+
+func TestProbesHandler_HandleLiveness(t *testing.T) {
+	handler := newTestProbesHandler()
+
+	rec := httptest.NewRecorder()
+	handler.HandleLiveness(rec, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response probeResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Equal(t, "ok", response.Status)
+	require.Empty(t, response.Reason)
+}
+
+func TestProbesHandler_checkLiveness_whenRefreshLoopStuck_thenFail(t *testing.T) {
+	handler := newTestProbesHandler()
+
+	require.Empty(t, handler.checkLiveness(time.Now().Add(2*time.Minute)))
+	require.Contains(t, handler.checkLiveness(time.Now().Add(4*time.Minute)), "no refresh finished since")
+}
+
+func TestProbesHandler_HandleReadiness_whenNoSuccessfulRefresh_thenNotReady(t *testing.T) {
+	handler := newTestProbesHandler()
+
+	rec := httptest.NewRecorder()
+	handler.HandleReadiness(rec, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var response probeResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Equal(t, "failing", response.Status)
+	require.Equal(t, "no successful refresh yet", response.Reason)
+	require.Zero(t, response.LastSuccessfulRefresh)
+}
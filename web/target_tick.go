@@ -0,0 +1,83 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+type targetTickResponse struct {
+	TargetTick     uint32  `json:"target_tick"`
+	MaxTick        uint32  `json:"max_tick"`
+	EstimatedTick  uint32  `json:"estimated_tick"`
+	Spread         uint32  `json:"spread"`
+	MarginTicks    uint32  `json:"margin_ticks"`
+	TickRateKnown  bool    `json:"tick_rate_known"`
+	TicksPerSecond float64 `json:"ticks_per_second"`
+	Eta            int64   `json:"eta,omitempty"`
+}
+
+// HandleTargetTick recommends a target tick for broadcasting transactions. The optional `margin` query parameter
+// overrides the configured safety margin, e.g. `30s`.
+func (h *PeersHandler) HandleTargetTick(w http.ResponseWriter, r *http.Request) {
+	margin := h.TargetTickMargin
+	if value := r.URL.Query().Get("margin"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, err := w.Write([]byte("invalid margin: " + value))
+			if err != nil {
+				log.Printf("Failed to respond to request: %v\n", err)
+			}
+			return
+		}
+		margin = parsed
+	}
+
+	if h.Container.IsInitializing() {
+		writeNotReady(w)
+		return
+	}
+
+	snapshot := h.Container.GetSnapshot()
+	if len(snapshot.ReliableNodes) == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, err := w.Write([]byte("No online or reliable nodes found."))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	target := snapshot.RecommendTargetTick(margin, h.TargetTickMinOffset, time.Now())
+	response := targetTickResponse{
+		TargetTick:     target.Tick,
+		MaxTick:        target.MaxTick,
+		EstimatedTick:  target.EstimatedTick,
+		Spread:         target.Spread,
+		MarginTicks:    target.MarginTicks,
+		TickRateKnown:  target.TickRateKnown,
+		TicksPerSecond: snapshot.TickRate.TicksPerSecond,
+	}
+	if eta, ok := snapshot.TickRate.EstimateTime(target.Tick); ok && target.TickRateKnown {
+		response.Eta = eta.Unix()
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Printf("Failed to respond to request: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Printf("Failed to write response for target tick request. Err: %v\n", err)
+	}
+}
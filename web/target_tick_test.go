@@ -0,0 +1,52 @@
+package web
+
+import (
+	"github.com/qubic/go-qubic-nodes/node"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_HandleTargetTick(t *testing.T) {
+	container := &node.Container{}
+	first := &node.Node{Address: "1.2.3.4", LastTick: 990}
+	second := &node.Node{Address: "2.3.4.5", LastTick: 1000}
+	container.Set([]*node.Node{first, second}, 1000, 0, []*node.Node{first, second}, second, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	handler := PeersHandler{Container: container, TargetTickMargin: 10 * time.Second, TargetTickMinOffset: 5}
+
+	rec := httptest.NewRecorder()
+	handler.HandleTargetTick(rec, httptest.NewRequest("GET", "/target-tick?margin=30s", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `{
+		"target_tick": 1010,
+		"max_tick": 1000,
+		"estimated_tick": 1000,
+		"spread": 10,
+		"margin_ticks": 0,
+		"tick_rate_known": false,
+		"ticks_per_second": 0
+	}`, rec.Body.String())
+}
+
+func TestHandler_HandleTargetTick_errors(t *testing.T) {
+	handler := PeersHandler{Container: &node.Container{}}
+
+	rec := httptest.NewRecorder()
+	handler.HandleTargetTick(rec, httptest.NewRequest("GET", "/target-tick?margin=-1s", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.HandleTargetTick(rec, httptest.NewRequest("GET", "/target-tick", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	peerManager := node.NewPeerManager([]string{"1.2.3.4"}, &node.NoPeerDiscovery{}, node.NewConnectionPool("12345", time.Second, time.Minute), node.NewWorkerPool(10), time.Second)
+	handler = PeersHandler{Container: node.NewNodeContainer(peerManager, 50, 30, node.NewQuorumCount(1), nil, 0)}
+	rec = httptest.NewRecorder()
+	handler.HandleTargetTick(rec, httptest.NewRequest("GET", "/target-tick", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"status": "initializing", "message": "Waiting for the first successful refresh."}`, rec.Body.String())
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"crypto/tls"
+	"github.com/pkg/errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateReloader serves a TLS certificate from files and reloads it once the files changed. The files are checked
+// at most once per check interval during handshakes. If reloading fails the previous certificate is kept.
+type CertificateReloader struct {
+	certFile      string
+	keyFile       string
+	checkInterval time.Duration
+	certificate   *tls.Certificate
+	modTimes      [2]time.Time
+	lastCheck     time.Time
+	lock          sync.Mutex
+}
+
+func NewCertificateReloader(certFile, keyFile string, checkInterval time.Duration) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		checkInterval: checkInterval,
+	}
+	modTimes, err := reloader.readModTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := reloader.load(modTimes); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (cr *CertificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	if time.Since(cr.lastCheck) >= cr.checkInterval {
+		cr.lastCheck = time.Now()
+		modTimes, err := cr.readModTimes()
+		if err != nil {
+			log.Printf("Failed to check TLS certificate: %v\n", err)
+		} else if modTimes != cr.modTimes {
+			if err := cr.load(modTimes); err != nil {
+				log.Printf("Failed to reload TLS certificate: %v\n", err)
+			} else {
+				log.Printf("Reloaded TLS certificate.\n")
+			}
+		}
+	}
+	return cr.certificate, nil
+}
+
+func (cr *CertificateReloader) load(modTimes [2]time.Time) error {
+	certificate, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "loading TLS certificate")
+	}
+	cr.certificate = &certificate
+	cr.modTimes = modTimes
+	return nil
+}
+
+func (cr *CertificateReloader) readModTimes() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, file := range []string{cr.certFile, cr.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, errors.Wrap(err, "reading TLS certificate file")
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func serialOf(t *testing.T, reloader *CertificateReloader) int64 {
+	certificate, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+	return parsed.SerialNumber.Int64()
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	start := time.Now().Add(-time.Minute)
+	writeTestCertificate(t, certFile, keyFile, 1, start)
+
+	reloader, err := NewCertificateReloader(certFile, keyFile, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), serialOf(t, reloader))
+
+	writeTestCertificate(t, certFile, keyFile, 2, start.Add(time.Second))
+	require.Equal(t, int64(2), serialOf(t, reloader))
+
+	// a broken certificate keeps the previous one
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0600))
+	require.NoError(t, os.Chtimes(certFile, start.Add(2*time.Second), start.Add(2*time.Second)))
+	require.Equal(t, int64(2), serialOf(t, reloader))
+}
+
+func TestCertificateReloader_checksOncePerInterval(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	start := time.Now().Add(-time.Minute)
+	writeTestCertificate(t, certFile, keyFile, 1, start)
+
+	reloader, err := NewCertificateReloader(certFile, keyFile, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), serialOf(t, reloader))
+
+	writeTestCertificate(t, certFile, keyFile, 2, start.Add(time.Second))
+	require.Equal(t, int64(1), serialOf(t, reloader))
+}
+
+func TestNewCertificateReloader_whenFilesMissing_thenError(t *testing.T) {
+	_, err := NewCertificateReloader("missing.pem", "missing-key.pem", time.Minute)
+	require.Error(t, err)
+}
@@ -0,0 +1,311 @@
+package web
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/qubic/go-qubic-nodes/node"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	topicMaxTick       = "max_tick"
+	topicReliableNodes = "reliable_nodes"
+	topicNodeStatus    = "node_status"
+	topicEpoch         = "epoch"
+)
+
+var topics = []string{topicMaxTick, topicReliableNodes, topicNodeStatus, topicEpoch}
+
+const (
+	pingInterval = 30 * time.Second
+	pongTimeout  = 2 * pingInterval
+	writeTimeout = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{}
+
+type subscriptionRequest struct {
+	Action       string   `json:"action"`
+	Topics       []string `json:"topics"`
+	LastSequence *uint64  `json:"last_sequence,omitempty"`
+}
+
+type webSocketMessage struct {
+	Type     string   `json:"type"`
+	Sequence uint64   `json:"sequence"`
+	Snapshot bool     `json:"snapshot,omitempty"`
+	Topics   []string `json:"topics,omitempty"`
+	Data     any      `json:"data,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type maxTickMessage struct {
+	MaxTick         uint32 `json:"max_tick"`
+	PreviousMaxTick uint32 `json:"previous_max_tick"`
+	LastUpdate      int64  `json:"last_update"`
+	Degraded        bool   `json:"degraded"`
+}
+
+type reliableNodesMessage struct {
+	ReliableNodes    []string `json:"reliable_nodes"`
+	Added            []string `json:"added"`
+	Removed          []string `json:"removed"`
+	MostReliableNode string   `json:"most_reliable_node"`
+}
+
+type epochMessage struct {
+	Epoch         uint16 `json:"epoch"`
+	PreviousEpoch uint16 `json:"previous_epoch"`
+	InitialTick   uint32 `json:"initial_tick"`
+	TickDuration  uint16 `json:"tick_duration"`
+}
+
+type nodeStatusMessage struct {
+	Nodes []nodeStatus `json:"nodes"`
+}
+
+type nodeStatus struct {
+	Address    string `json:"address"`
+	LastTick   uint32 `json:"last_tick"`
+	Lag        uint32 `json:"lag"`
+	Reliable   bool   `json:"reliable"`
+	LastUpdate int64  `json:"last_update"`
+}
+
+// HandleWebSocket lets clients subscribe to topics and receive a message per topic whenever the container is
+// updated. Clients can resume after a reconnect by passing the last seen sequence number when subscribing. The
+// connection is closed once the request context is done, for example on server shutdown.
+func (h *PeersHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade websocket connection: %v\n", err)
+		return
+	}
+	defer conn.Close()
+
+	// subscribe before replaying, duplicates are skipped by sequence number
+	updates, unsubscribe := h.Container.SubscribeUpdates()
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	defer close(done)
+	requests := make(chan subscriptionRequest)
+	go readSubscriptionRequests(conn, requests, done)
+
+	session := &webSocketSession{
+		conn:       conn,
+		container:  h.Container,
+		subscribed: make(map[string]bool),
+	}
+
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case request, open := <-requests:
+			if !open {
+				return
+			}
+			err = session.handleRequest(request)
+		case update, open := <-updates:
+			if !open {
+				return
+			}
+			err = session.sendUpdate(update)
+		case <-pingTicker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+		}
+		if err != nil {
+			log.Printf("Closing websocket connection: %v\n", err)
+			return
+		}
+	}
+}
+
+func readSubscriptionRequests(conn *websocket.Conn, requests chan<- subscriptionRequest, done <-chan struct{}) {
+	defer close(requests)
+
+	_ = conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		var request subscriptionRequest
+		if err := conn.ReadJSON(&request); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("Failed to read websocket message: %v\n", err)
+			}
+			return
+		}
+		select {
+		case requests <- request:
+		case <-done:
+			return
+		}
+	}
+}
+
+type webSocketSession struct {
+	conn         *websocket.Conn
+	container    *node.Container
+	subscribed   map[string]bool
+	lastSequence uint64
+}
+
+func (s *webSocketSession) handleRequest(request subscriptionRequest) error {
+	for _, topic := range request.Topics {
+		if !slices.Contains(topics, topic) {
+			return s.write(webSocketMessage{Type: "error", Error: "unknown topic: " + topic})
+		}
+	}
+
+	switch request.Action {
+	case "subscribe":
+		for _, topic := range request.Topics {
+			s.subscribed[topic] = true
+		}
+		latest := s.container.GetLatestUpdate()
+		err := s.write(webSocketMessage{Type: "subscribed", Sequence: latest.Sequence, Topics: request.Topics})
+		if err != nil {
+			return err
+		}
+		return s.catchUp(request, latest)
+	case "unsubscribe":
+		for _, topic := range request.Topics {
+			delete(s.subscribed, topic)
+		}
+		return s.write(webSocketMessage{Type: "unsubscribed", Sequence: s.lastSequence, Topics: request.Topics})
+	default:
+		return s.write(webSocketMessage{Type: "error", Error: "unknown action: " + request.Action})
+	}
+}
+
+// catchUp replays the missed updates of the requested topics or sends a snapshot, if the client does not resume or
+// the missed updates are not available anymore.
+func (s *webSocketSession) catchUp(request subscriptionRequest, latest node.ContainerUpdate) error {
+	if request.LastSequence != nil {
+		missed, ok := s.container.GetUpdatesSince(*request.LastSequence)
+		if ok {
+			// the replay can contain updates newer than the latest update, which are skipped when they arrive
+			for _, update := range missed {
+				if err := s.sendTopics(update, request.Topics, false); err != nil {
+					return err
+				}
+				s.lastSequence = max(s.lastSequence, update.Sequence)
+			}
+			return nil
+		}
+	}
+	if latest.Sequence == 0 {
+		return nil
+	}
+	s.lastSequence = max(s.lastSequence, latest.Sequence)
+	return s.sendTopics(latest, request.Topics, true)
+}
+
+func (s *webSocketSession) sendUpdate(update node.ContainerUpdate) error {
+	if update.Sequence <= s.lastSequence {
+		return nil
+	}
+	s.lastSequence = update.Sequence
+
+	var subscribed []string
+	for _, topic := range topics {
+		if s.subscribed[topic] {
+			subscribed = append(subscribed, topic)
+		}
+	}
+	return s.sendTopics(update, subscribed, false)
+}
+
+// sendTopics sends a message per topic. Unless a snapshot is requested, messages are only sent for topics that
+// changed with the update.
+func (s *webSocketSession) sendTopics(update node.ContainerUpdate, topicsToSend []string, snapshot bool) error {
+	event := update.Event
+	for _, topic := range topicsToSend {
+		var data any
+		switch topic {
+		case topicMaxTick:
+			if !snapshot && !event.MaxTickChanged {
+				continue
+			}
+			data = maxTickMessage{
+				MaxTick:         update.MaxTick,
+				PreviousMaxTick: event.PreviousMaxTick,
+				LastUpdate:      update.LastUpdate,
+				Degraded:        update.Degraded,
+			}
+		case topicReliableNodes:
+			if !snapshot && !event.MostReliableNodeChanged && len(event.ReliableNodesAdded) == 0 && len(event.ReliableNodesRemoved) == 0 {
+				continue
+			}
+			data = newReliableNodesMessage(update, snapshot)
+		case topicNodeStatus:
+			data = newNodeStatusMessage(update)
+		case topicEpoch:
+			if !snapshot && !event.EpochChanged {
+				continue
+			}
+			data = epochMessage{
+				Epoch:         update.Epoch.Epoch,
+				PreviousEpoch: event.PreviousEpoch,
+				InitialTick:   update.Epoch.InitialTick,
+				TickDuration:  update.Epoch.TickDuration,
+			}
+		}
+		err := s.write(webSocketMessage{Type: topic, Sequence: update.Sequence, Snapshot: snapshot, Data: data})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *webSocketSession) write(message webSocketMessage) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteJSON(message)
+}
+
+func newReliableNodesMessage(update node.ContainerUpdate, snapshot bool) reliableNodesMessage {
+	message := reliableNodesMessage{
+		ReliableNodes: make([]string, 0, len(update.ReliableNodes)),
+		Added:         emptyIfNil(update.Event.ReliableNodesAdded),
+		Removed:       emptyIfNil(update.Event.ReliableNodesRemoved),
+	}
+	for _, reliable := range update.ReliableNodes {
+		message.ReliableNodes = append(message.ReliableNodes, reliable.Address)
+	}
+	if snapshot {
+		message.Added = message.ReliableNodes
+		message.Removed = []string{}
+	}
+	if update.MostReliableNode != nil {
+		message.MostReliableNode = update.MostReliableNode.Address
+	}
+	return message
+}
+
+func newNodeStatusMessage(update node.ContainerUpdate) nodeStatusMessage {
+	message := nodeStatusMessage{Nodes: make([]nodeStatus, 0, len(update.OnlineNodes))}
+	for _, online := range update.OnlineNodes {
+		reliable := slices.ContainsFunc(update.ReliableNodes, func(reliable node.Node) bool {
+			return reliable.Address == online.Address
+		})
+		message.Nodes = append(message.Nodes, nodeStatus{
+			Address:    online.Address,
+			LastTick:   online.LastTick,
+			Lag:        online.TickLag(update.MaxTick),
+			Reliable:   reliable,
+			LastUpdate: online.LastUpdate,
+		})
+	}
+	return message
+}
@@ -0,0 +1,183 @@
+package web
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/qubic/go-qubic-nodes/node"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testMessage struct {
+	Type     string         `json:"type"`
+	Sequence uint64         `json:"sequence"`
+	Snapshot bool           `json:"snapshot"`
+	Topics   []string       `json:"topics"`
+	Data     map[string]any `json:"data"`
+	Error    string         `json:"error"`
+}
+
+func dialWebSocket(t *testing.T, container *node.Container) *websocket.Conn {
+	handler := PeersHandler{Container: container}
+	server := httptest.NewServer(http.HandlerFunc(handler.HandleWebSocket))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) testMessage {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var message testMessage
+	require.NoError(t, conn.ReadJSON(&message))
+	return message
+}
+
+func TestHandler_HandleWebSocket_subscribe(t *testing.T) {
+	node1 := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	node2 := &node.Node{Address: "2.3.4.5", LastTick: 90}
+	container := &node.Container{}
+	container.Set([]*node.Node{node1, node2}, 100, 1500000000, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	conn := dialWebSocket(t, container)
+	require.NoError(t, conn.WriteJSON(subscriptionRequest{Action: "subscribe", Topics: []string{topicMaxTick, topicNodeStatus}}))
+
+	ack := readMessage(t, conn)
+	require.Equal(t, "subscribed", ack.Type)
+	require.Equal(t, uint64(1), ack.Sequence)
+
+	snapshot := readMessage(t, conn)
+	require.Equal(t, topicMaxTick, snapshot.Type)
+	require.True(t, snapshot.Snapshot)
+	require.Equal(t, 100.0, snapshot.Data["max_tick"])
+
+	status := readMessage(t, conn)
+	require.Equal(t, topicNodeStatus, status.Type)
+	require.Len(t, status.Data["nodes"], 2)
+	lagging := status.Data["nodes"].([]any)[1].(map[string]any)
+	require.Equal(t, "2.3.4.5", lagging["address"])
+	require.Equal(t, 10.0, lagging["lag"])
+	require.Equal(t, false, lagging["reliable"])
+
+	// reliable nodes unchanged, only max tick and node status are sent
+	container.Set([]*node.Node{node1}, 101, 1500000001, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	update := readMessage(t, conn)
+	require.Equal(t, topicMaxTick, update.Type)
+	require.Equal(t, uint64(2), update.Sequence)
+	require.False(t, update.Snapshot)
+	require.Equal(t, 101.0, update.Data["max_tick"])
+	require.Equal(t, 100.0, update.Data["previous_max_tick"])
+	require.Equal(t, topicNodeStatus, readMessage(t, conn).Type)
+}
+
+func TestHandler_HandleWebSocket_resume(t *testing.T) {
+	node1 := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	node2 := &node.Node{Address: "2.3.4.5", LastTick: 100}
+	container := &node.Container{}
+	container.Set([]*node.Node{node1}, 100, 1500000000, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	container.Set([]*node.Node{node1, node2}, 100, 1500000001, []*node.Node{node1, node2}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	container.Set([]*node.Node{node2}, 100, 1500000002, []*node.Node{node2}, node2, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	conn := dialWebSocket(t, container)
+	lastSequence := uint64(1)
+	require.NoError(t, conn.WriteJSON(subscriptionRequest{Action: "subscribe", Topics: []string{topicReliableNodes}, LastSequence: &lastSequence}))
+
+	require.Equal(t, "subscribed", readMessage(t, conn).Type)
+
+	added := readMessage(t, conn)
+	require.Equal(t, uint64(2), added.Sequence)
+	require.False(t, added.Snapshot)
+	require.Equal(t, []any{"2.3.4.5"}, added.Data["added"])
+
+	removed := readMessage(t, conn)
+	require.Equal(t, uint64(3), removed.Sequence)
+	require.Equal(t, []any{"1.2.3.4"}, removed.Data["removed"])
+	require.Equal(t, "2.3.4.5", removed.Data["most_reliable_node"])
+}
+
+func TestWebSocketSession_catchUp_whenUpdatedBeforeReplay_thenSkipReplayedUpdates(t *testing.T) {
+	node1 := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	node2 := &node.Node{Address: "2.3.4.5", LastTick: 100}
+	container := &node.Container{}
+	container.Set([]*node.Node{node1}, 100, 1500000000, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	latest := container.GetLatestUpdate()
+	container.Set([]*node.Node{node1, node2}, 100, 1500000001, []*node.Node{node1, node2}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	updates, ok := container.GetUpdatesSince(latest.Sequence)
+	require.True(t, ok)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		session := &webSocketSession{conn: conn, container: container, subscribed: map[string]bool{topicReliableNodes: true}}
+
+		lastSequence := latest.Sequence
+		// the update of the replay arrives after the replay
+		assert.NoError(t, session.catchUp(subscriptionRequest{Topics: []string{topicReliableNodes}, LastSequence: &lastSequence}, latest))
+		assert.NoError(t, session.sendUpdate(updates[0]))
+		assert.NoError(t, session.write(webSocketMessage{Type: "done"}))
+	}))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	added := readMessage(t, conn)
+	require.Equal(t, uint64(2), added.Sequence)
+	require.Equal(t, []any{"2.3.4.5"}, added.Data["added"])
+	require.Equal(t, "done", readMessage(t, conn).Type)
+}
+
+func TestHandler_HandleWebSocket_resumeUnknownSequenceSendsSnapshot(t *testing.T) {
+	node1 := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	container := &node.Container{}
+	container.Set([]*node.Node{node1}, 100, 1500000000, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	conn := dialWebSocket(t, container)
+	lastSequence := uint64(42)
+	require.NoError(t, conn.WriteJSON(subscriptionRequest{Action: "subscribe", Topics: []string{topicReliableNodes}, LastSequence: &lastSequence}))
+
+	require.Equal(t, "subscribed", readMessage(t, conn).Type)
+	snapshot := readMessage(t, conn)
+	require.True(t, snapshot.Snapshot)
+	require.Equal(t, []any{"1.2.3.4"}, snapshot.Data["reliable_nodes"])
+}
+
+func TestHandler_HandleWebSocket_unknownTopic(t *testing.T) {
+	conn := dialWebSocket(t, &node.Container{})
+	require.NoError(t, conn.WriteJSON(subscriptionRequest{Action: "subscribe", Topics: []string{"unknown"}}))
+
+	message := readMessage(t, conn)
+	require.Equal(t, "error", message.Type)
+	require.Equal(t, "unknown topic: unknown", message.Error)
+}
+
+func TestHandler_HandleWebSocket_epoch(t *testing.T) {
+	node1 := &node.Node{Address: "1.2.3.4", LastTick: 100}
+	container := &node.Container{}
+	container.Set([]*node.Node{node1}, 100, 1500000000, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+
+	conn := dialWebSocket(t, container)
+	require.NoError(t, conn.WriteJSON(subscriptionRequest{Action: "subscribe", Topics: []string{topicEpoch}}))
+	require.Equal(t, "subscribed", readMessage(t, conn).Type)
+
+	snapshot := readMessage(t, conn)
+	require.Equal(t, topicEpoch, snapshot.Type)
+	require.True(t, snapshot.Snapshot)
+	require.Equal(t, 0.0, snapshot.Data["epoch"])
+
+	// unchanged epoch is not sent again
+	container.Set([]*node.Node{node1}, 101, 1500000001, []*node.Node{node1}, node1, nil, false, node.StallStatus{}, node.EpochInfo{}, node.TickRate{})
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err := conn.ReadMessage()
+	require.Error(t, err)
+}